@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// maxScriptLine bounds the length of one line of an operation script.
+const maxScriptLine = 1 << 20
+
+// scriptOp is one line of an operation script read by ApplyFrom.
+type scriptOp struct {
+	Op     string `json:"op"`               // OpRead or OpWrite
+	Value  string `json:"value,omitempty"`  // Data stored by a write
+	Worker int    `json:"worker,omitempty"` // ID of the worker issuing the operation; zero is anonymous
+}
+
+// ApplyFrom applies the operations of a newline-delimited JSON script to the resource in order,
+// one object per line such as {"op": "write", "value": "x", "worker": 1}, and returns their statistics.
+// Blank lines are skipped. A malformed line or failing operation stops the script with an error
+// naming its line number; the statistics cover the operations applied until then.
+// The result's Events are filled only if the resource records its timeline (see WithEventTimeline).
+func (r *Resource) ApplyFrom(ctx context.Context, src io.Reader) (SimulationResult, error) {
+	result := newSimulationResult(1)
+	firstEvent := len(r.Events())
+
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(nil, maxScriptLine)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var op scriptOp
+		if err := json.Unmarshal(scanner.Bytes(), &op); err != nil {
+			return r.appliedResult(result, firstEvent), fmt.Errorf("line %d: malformed operation: %w", line, err)
+		}
+
+		opCtx := ctx
+		if op.Worker != 0 {
+			opCtx = WithIdentity(ctx, WorkerIdentity{ID: op.Worker})
+		}
+		switch op.Op {
+		case OpRead:
+			_, err := r.Read(opCtx)
+			result.recordRead(0, err)
+			if err != nil {
+				return r.appliedResult(result, firstEvent), fmt.Errorf("line %d: %w", line, err)
+			}
+		case OpWrite:
+			err := r.Write(opCtx, op.Value)
+			result.recordWrite(0, op.Worker, err)
+			if err != nil {
+				return r.appliedResult(result, firstEvent), fmt.Errorf("line %d: %w", line, err)
+			}
+		default:
+			return r.appliedResult(result, firstEvent), fmt.Errorf("line %d: unknown operation %q", line, op.Op)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return r.appliedResult(result, firstEvent), fmt.Errorf("line %d: %w", line+1, err)
+	}
+
+	result = r.appliedResult(result, firstEvent)
+	data, err := r.Read(ctx)
+	if err != nil {
+		return result, err
+	}
+	result.FinalValue = data
+	result.Resources[0].FinalValue = data
+	return result, nil
+}
+
+// appliedResult attaches the events recorded since firstEvent to result.
+func (r *Resource) appliedResult(result SimulationResult, firstEvent int) SimulationResult {
+	if events := r.Events(); firstEvent < len(events) {
+		result.Events = events[firstEvent:]
+	}
+	return result
+}
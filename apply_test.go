@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func FuzzParsePlan(f *testing.F) {
+	for _, seed := range []string{
+		`{"op": "write", "value": "x", "worker": 1}`,
+		"{\"op\": \"read\"}\n\n{\"op\": \"write\", \"value\": \"y\"}\n",
+		`{"op": "delete"}`,
+		`{"op": "write", "value": 7}`,
+		`{"op": "write"`,
+		"not json\n",
+		"{\"op\": \"write\", \"value\": \"a\\nb\"}\r\n",
+		"",
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, script []byte) {
+		r := NewResource(initialData)
+		result, err := r.ApplyFrom(context.Background(), bytes.NewReader(script))
+		if err != nil {
+			if !strings.HasPrefix(err.Error(), "line ") {
+				t.Fatalf("error %q does not name the failing line", err)
+			}
+		} else if result.FinalValue == "" && result.Writes == 0 {
+			t.Fatalf("final value lost on a script without writes: %+v", result)
+		}
+		if result.Failures != 0 {
+			t.Fatalf("got %d failed operations against a healthy resource", result.Failures)
+		}
+	})
+}
+
+func TestApplyFrom(t *testing.T) {
+	r := NewResource("initial", WithEventTimeline())
+	script := `{"op": "read"}
+{"op": "write", "value": "a", "worker": 1}
+
+{"op": "write", "value": "b", "worker": 2}
+{"op": "read", "worker": 1}
+`
+	result, err := r.ApplyFrom(context.Background(), strings.NewReader(script))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Reads != 2 || result.Writes != 2 || result.FinalValue != "b" {
+		t.Fatalf("got %d reads, %d writes and final value %q, want 2, 2 and \"b\"", result.Reads, result.Writes, result.FinalValue)
+	}
+	if len(result.Events) != 4 {
+		t.Fatalf("got %d events, want one per scripted operation", len(result.Events))
+	}
+	if ev := result.Events[2]; ev.Op != OpWrite || ev.Value != "b" || ev.Worker.ID != 2 {
+		t.Fatalf("third event %+v, want worker 2 writing \"b\"", ev)
+	}
+}
+
+func TestApplyFromMalformedLine(t *testing.T) {
+	r := NewResource("initial")
+	script := "{\"op\": \"write\", \"value\": \"a\"}\n\n{\"op\": \"write\"\n{\"op\": \"write\", \"value\": \"never\"}\n"
+	result, err := r.ApplyFrom(context.Background(), strings.NewReader(script))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 3:") {
+		t.Fatalf("got error %v, want one naming line 3", err)
+	}
+	if result.Writes != 1 {
+		t.Fatalf("got %d writes, want only the one before the malformed line", result.Writes)
+	}
+	if data, _ := r.Read(context.Background()); data != "a" {
+		t.Fatalf("stored %q, want the script to stop at the malformed line", data)
+	}
+
+	if _, err := r.ApplyFrom(context.Background(), strings.NewReader(`{"op": "delete"}`)); err == nil || !strings.Contains(err.Error(), "unknown operation") {
+		t.Fatalf("got error %v, want an unknown operation", err)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import "context"
+
+// AsyncResult is the outcome of an asynchronous operation.
+type AsyncResult struct {
+	Data string // Data read; empty for writes
+	Err  error  // Error returned by the operation, if any
+}
+
+// AsyncOperation is a handle to an operation running in the background.
+type AsyncOperation struct {
+	Result <-chan AsyncResult // Receives exactly one result once the operation finishes
+	cancel context.CancelFunc
+}
+
+// Cancel abandons the operation. An operation still waiting for the lock gives up
+// and delivers context.Canceled; one that already completed is unaffected.
+func (op *AsyncOperation) Cancel() {
+	op.cancel()
+}
+
+// ReadAsync starts a read in the background and returns a handle to it.
+func (r *Resource) ReadAsync(ctx context.Context) *AsyncOperation {
+	return runAsync(ctx, func(ctx context.Context) AsyncResult {
+		data, err := r.Read(ctx)
+		return AsyncResult{Data: data, Err: err}
+	})
+}
+
+// WriteAsync starts a write in the background and returns a handle to it.
+func (r *Resource) WriteAsync(ctx context.Context, newData string) *AsyncOperation {
+	return runAsync(ctx, func(ctx context.Context) AsyncResult {
+		return AsyncResult{Err: r.Write(ctx, newData)}
+	})
+}
+
+// runAsync runs op in a goroutine under a cancelable copy of ctx.
+func runAsync(ctx context.Context, op func(ctx context.Context) AsyncResult) *AsyncOperation {
+	ctx, cancel := context.WithCancel(ctx)
+	result := make(chan AsyncResult, 1) // Buffered so the goroutine never blocks if the result is ignored
+	go func() {
+		defer cancel()
+		result <- op(ctx)
+	}()
+	return &AsyncOperation{Result: result, cancel: cancel}
+}
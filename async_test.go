@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWriteAsyncCancelWhileBlocked(t *testing.T) {
+	r := NewResource("initial")
+	tx, err := r.Begin(context.Background()) // Hold the write lock so the async write queues
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	op := r.WriteAsync(context.Background(), "abandoned")
+	for r.WaitQueueDepth() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	op.Cancel()
+
+	select {
+	case res := <-op.Result:
+		if !errors.Is(res.Err, context.Canceled) {
+			t.Fatalf("got %v, want context.Canceled", res.Err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("canceled write is still waiting for the lock")
+	}
+	tx.Rollback()
+	if data, _ := r.Read(context.Background()); data != "initial" {
+		t.Fatalf("canceled write landed: got %q", data)
+	}
+}
+
+func TestReadAsyncDeliversData(t *testing.T) {
+	r := NewResource("initial")
+	op := r.ReadAsync(context.Background())
+	if res := <-op.Result; res.Err != nil || res.Data != "initial" {
+		t.Fatalf("got %q (%v)", res.Data, res.Err)
+	}
+	op.Cancel() // Canceling a finished operation is harmless
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by a CircuitBreaker that is short-circuiting operations.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets every operation through.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects every operation until the cooldown elapses.
+	BreakerOpen
+	// BreakerHalfOpen lets a single probe operation through to test recovery.
+	BreakerHalfOpen
+)
+
+// String returns the name of the state.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker stops operations against a struggling resource. It opens after a number of
+// consecutive failures, rejects operations with ErrCircuitOpen for a cooldown, and then
+// half-opens to let one probe through: success closes it again, failure reopens it.
+type CircuitBreaker struct {
+	threshold int           // Consecutive failures that open the breaker
+	cooldown  time.Duration // Time spent open before probing
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int       // Consecutive failures while closed
+	openedAt time.Time // When the breaker last opened
+	probing  bool      // Whether the half-open probe is in flight
+}
+
+// NewCircuitBreaker creates a new CircuitBreaker that opens after threshold consecutive failures
+// and stays open for cooldown.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// State returns the current state, moving from open to half-open once the cooldown has elapsed.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	return b.state
+}
+
+// Do runs op if the breaker allows it and records the outcome.
+// It returns ErrCircuitOpen without running op while the breaker is open or a probe is in flight.
+func (b *CircuitBreaker) Do(op func() error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := op()
+	b.record(err)
+	return err
+}
+
+// allow decides whether an operation may run.
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	switch b.state {
+	case BreakerOpen:
+		return ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen // Only one probe at a time
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// record updates the state with the outcome of an operation.
+func (b *CircuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerHalfOpen {
+		b.probing = false
+		if err != nil {
+			b.open()
+			return
+		}
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+
+	if err == nil {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.open()
+	}
+}
+
+// open trips the breaker. b.mu must be held.
+func (b *CircuitBreaker) open() {
+	b.state = BreakerOpen
+	b.openedAt = time.Now()
+	b.failures = 0
+}
+
+// advance moves an open breaker to half-open once the cooldown has elapsed. b.mu must be held.
+func (b *CircuitBreaker) advance() {
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cooldown {
+		b.state = BreakerHalfOpen
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerStates(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+	b := NewCircuitBreaker(2, cooldown)
+	errBusy := errors.New("busy")
+	fail := func() error { return errBusy }
+	succeed := func() error { return nil }
+
+	b.Do(fail)
+	if s := b.State(); s != BreakerClosed {
+		t.Fatalf("after one failure: %s, want closed", s)
+	}
+	b.Do(fail)
+	if s := b.State(); s != BreakerOpen {
+		t.Fatalf("after two failures: %s, want open", s)
+	}
+	ran := false
+	if err := b.Do(func() error { ran = true; return nil }); !errors.Is(err, ErrCircuitOpen) || ran {
+		t.Fatalf("open breaker got %v with ran %v, want the operation short-circuited", err, ran)
+	}
+
+	time.Sleep(cooldown)
+	if s := b.State(); s != BreakerHalfOpen {
+		t.Fatalf("after the cooldown: %s, want half-open", s)
+	}
+	if err := b.Do(succeed); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if s := b.State(); s != BreakerClosed {
+		t.Fatalf("after a successful probe: %s, want closed", s)
+	}
+}
+
+func TestCircuitBreakerFailedProbeReopens(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+	b := NewCircuitBreaker(1, cooldown)
+	errBusy := errors.New("busy")
+	b.Do(func() error { return errBusy })
+	time.Sleep(cooldown)
+
+	if err := b.Do(func() error { return errBusy }); err != errBusy {
+		t.Fatalf("probe got %v", err)
+	}
+	if s := b.State(); s != BreakerOpen {
+		t.Fatalf("after a failed probe: %s, want open", s)
+	}
+}
+
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	const cooldown = 10 * time.Millisecond
+	b := NewCircuitBreaker(1, cooldown)
+	b.Do(func() error { return errors.New("busy") })
+	time.Sleep(cooldown)
+
+	probing := make(chan struct{})
+	release := make(chan struct{})
+	go b.Do(func() error {
+		close(probing)
+		<-release
+		return nil
+	})
+	<-probing
+	if err := b.Do(func() error { return nil }); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second operation during the probe got %v, want ErrCircuitOpen", err)
+	}
+	close(release)
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	b := NewCircuitBreaker(2, time.Hour)
+	errBusy := errors.New("busy")
+	b.Do(func() error { return errBusy })
+	b.Do(func() error { return nil })
+	b.Do(func() error { return errBusy })
+	if s := b.State(); s != BreakerClosed {
+		t.Fatalf("got %s, want the failures to be counted consecutively", s)
+	}
+}
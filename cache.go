@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// cachedRead is a value taken from a consistent read, and when it was taken.
+type cachedRead struct {
+	data string
+	at   time.Time
+}
+
+// ReadWithin returns a cached value if it was read from the resource no more than maxStale ago,
+// without taking the lock. Otherwise it falls through to a consistent read and refreshes the cache.
+// Larger bounds trade freshness for less lock contention.
+func (r *Resource) ReadWithin(ctx context.Context, maxStale time.Duration) (string, error) {
+	if c := r.cache.Load(); c != nil && time.Since(c.at) <= maxStale {
+		return c.data, nil
+	}
+	at := time.Now() // Staleness counts from before the read began
+	data, err := r.Read(ctx)
+	if err != nil {
+		return "", err
+	}
+	r.storeCache(&cachedRead{data: data, at: at})
+	return data, nil
+}
+
+// storeCache replaces the cached read unless a fresher one was stored concurrently.
+func (r *Resource) storeCache(c *cachedRead) {
+	for {
+		old := r.cache.Load()
+		if old != nil && old.at.After(c.at) {
+			return
+		}
+		if r.cache.CompareAndSwap(old, c) {
+			return
+		}
+	}
+}
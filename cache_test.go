@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReadWithinServesFreshCache(t *testing.T) {
+	r := NewResource("initial")
+	ctx := context.Background()
+	if data, err := r.ReadWithin(ctx, time.Hour); err != nil || data != "initial" {
+		t.Fatalf("first read: got %q (%v)", data, err)
+	}
+	r.Write(ctx, "updated")
+
+	// The cached value is within the bound, so it is served without waiting for the held lock
+	var data string
+	elapsed, err := blockedFor(t, r, func() error {
+		var err error
+		data, err = r.ReadWithin(ctx, time.Hour)
+		return err
+	})
+	if err != nil || data != "initial" || elapsed > 100*time.Millisecond {
+		t.Fatalf("got %q (%v) after %v, want the cached value at once", data, err, elapsed)
+	}
+}
+
+func TestReadWithinFallsThroughWhenStale(t *testing.T) {
+	const maxStale = 5 * time.Millisecond
+	r := NewResource("initial")
+	ctx := context.Background()
+	r.ReadWithin(ctx, maxStale)
+	r.Write(ctx, "updated")
+	time.Sleep(2 * maxStale)
+
+	if data, err := r.ReadWithin(ctx, maxStale); err != nil || data != "updated" {
+		t.Fatalf("got %q (%v), want a primary read of the new value", data, err)
+	}
+	if data, _ := r.ReadWithin(ctx, time.Hour); data != "updated" {
+		t.Fatalf("cache not refreshed by the primary read: got %q", data)
+	}
+}
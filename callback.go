@@ -0,0 +1,21 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrCallbackPanic is wrapped by the error returned when a user callback panics while a lock is held.
+var ErrCallbackPanic = errors.New("callback panicked")
+
+// callSafely runs fn and converts a panic into an error wrapping ErrCallbackPanic and the panic value.
+// Callbacks run while a lock is held, so recovering keeps one bad callback from taking down the
+// process, and lets the caller's deferred unlock release the lock normally.
+func callSafely(fn func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("%w: %v", ErrCallbackPanic, v)
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPanickingCallbacksReleaseLock(t *testing.T) {
+	ops := map[string]func(r *Resource) error{
+		"WithReadLock": func(r *Resource) error {
+			return r.WithReadLock(context.Background(), func(string) error { panic("boom") })
+		},
+		"Patch": func(r *Resource) error {
+			_, err := r.Patch(context.Background(), func(string) (string, bool) { panic("boom") })
+			return err
+		},
+		"WriteAndDowngrade": func(r *Resource) error {
+			return r.WriteAndDowngrade(context.Background(), "x", func(string) error { panic("boom") })
+		},
+	}
+	for name, op := range ops {
+		r := NewResource("initial")
+		err := op(r)
+		if !errors.Is(err, ErrCallbackPanic) || !strings.Contains(err.Error(), "boom") {
+			t.Errorf("%s: got %v, want ErrCallbackPanic with the panic value", name, err)
+			continue
+		}
+
+		// Writing needs the lock to have been fully released
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		if err := r.Write(ctx, "after"); err != nil {
+			t.Errorf("%s: lock still held after the panic: %v", name, err)
+		}
+		cancel()
+	}
+}
+
+func TestPanickingRangeCallbackReleasesShards(t *testing.T) {
+	k := NewKeyedResource(2, WithInitialData(map[string]string{"a": "1"}))
+	err := k.Range(context.Background(), func(string, string) bool { panic("boom") })
+	if !errors.Is(err, ErrCallbackPanic) {
+		t.Fatalf("got %v, want ErrCallbackPanic", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := k.Write(ctx, "a", "2"); err != nil {
+		t.Fatalf("shard still locked after the panic: %v", err)
+	}
+}
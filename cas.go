@@ -0,0 +1,41 @@
+package main
+
+import "context"
+
+// CompareAndSwap stores newData only if the current data, as Read would return it, equals old,
+// and reports whether it did. The comparison and store happen atomically under the write lock.
+// A swap that does not happen is recorded as a read, since it only observed the data.
+func (r *Resource) CompareAndSwap(ctx context.Context, old, newData string) (bool, error) {
+	return r.Patch(ctx, func(current string) (string, bool) {
+		return newData, current == old
+	})
+}
+
+// Update applies fn optimistically: it reads the current data, computes the new value with fn
+// outside any lock, and stores it with CompareAndSwap. If a concurrent write changed the data
+// in between, the whole loop is retried until it succeeds or ctx is done.
+// fn may be called several times and must not have side effects.
+func (r *Resource) Update(ctx context.Context, fn func(old string) string) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err() // Return error if context is canceled
+		default:
+			old, err := r.Read(ctx)
+			if err != nil {
+				return err
+			}
+			var next string
+			if err := callSafely(func() error { next = fn(old); return nil }); err != nil {
+				return err
+			}
+			swapped, err := r.CompareAndSwap(ctx, old, next)
+			if err != nil {
+				return err
+			}
+			if swapped {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCompareAndSwap(t *testing.T) {
+	r := NewResource("initial")
+	ctx := context.Background()
+	if swapped, err := r.CompareAndSwap(ctx, "initial", "next"); err != nil || !swapped {
+		t.Fatalf("got swapped %v (%v), want a swap from the current value", swapped, err)
+	}
+	if swapped, _ := r.CompareAndSwap(ctx, "initial", "stale"); swapped {
+		t.Fatal("swapped from a stale value")
+	}
+	if data, _ := r.Read(ctx); data != "next" {
+		t.Fatalf("got %q", data)
+	}
+}
+
+func TestUpdateConcurrent(t *testing.T) {
+	r := NewResource("")
+	ctx := context.Background()
+	const updaters, perUpdater = 8, 25
+
+	var wg sync.WaitGroup
+	for u := 0; u < updaters; u++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perUpdater; i++ {
+				if err := r.Update(ctx, func(old string) string { return old + "x" }); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// A lost update would leave the value short of one x per call
+	if data, _ := r.Read(ctx); data != strings.Repeat("x", updaters*perUpdater) {
+		t.Fatalf("got %d updates applied, want %d", len(data), updaters*perUpdater)
+	}
+}
+
+func TestUpdateStopsOnContextDone(t *testing.T) {
+	r := NewResource("0")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// A competing writer changes the value on every attempt, so the CAS never succeeds
+	err := r.Update(ctx, func(old string) string {
+		r.Write(context.Background(), old+"!")
+		return "mine"
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the retry loop to end with the context", err)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Clone returns a new Resource holding a copy of the current data, version and configuration,
+// for forking a simulation from a known state. The clone has its own lock, counters, events,
+// subscribers and close hooks, so operations on either resource never affect the other.
+func (r *Resource) Clone() *Resource {
+	r.mu.RLock(context.Background()) // Acquire a read lock so data and version are copied together
+	defer r.mu.RUnlock()
+
+	c := &Resource{
+		id:                  nextResourceID(),
+		data:                r.data,
+		lastWriter:          r.lastWriter,
+		defaultTimeout:      r.defaultTimeout,
+		readTimeout:         r.readTimeout,
+		writeTimeout:        r.writeTimeout,
+		readOnly:            atomic.LoadInt32(&r.readOnly),
+		maxBytes:            r.maxBytes,
+		valueBytes:          int64(len(r.data)),
+		starvationThreshold: r.starvationThreshold,
+		slowThreshold:       atomic.LoadInt64(&r.slowThreshold),
+		transformers:        r.transformers,
+		validators:          r.validators,
+		newLock:             r.newLock,
+		timeline:            atomic.LoadInt32(&r.timeline),
+		closing:             make(chan struct{}),
+	}
+	c.mu = c.createLock()
+	c.metrics.sampler = r.metrics.sampler // Shared so both draw from the one random source under its lock
+	if r.idempotency != nil {
+		c.idempotency = newIdempotencyCache(r.idempotency.window)
+	}
+	if r.opLog != nil {
+		c.opLog = &operationLog{slots: make([]atomic.Pointer[logSlot], len(r.opLog.slots))}
+	}
+
+	if r.priority != nil {
+		c.priority = &priorityQueue{}
+		c.startPriorityQueue()
+	}
+
+	r.notifyMu.Lock()
+	c.version, c.lastWrite = r.version, r.lastWrite
+	r.notifyMu.Unlock()
+	return c
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCloneIsIndependent(t *testing.T) {
+	r := NewResource("initial")
+	ctx := context.Background()
+	r.Write(ctx, "original")
+
+	c := r.Clone()
+	if data, _ := c.Read(ctx); data != "original" || c.Version() != r.Version() {
+		t.Fatalf("clone holds %q at version %d, want a copy of the original", data, c.Version())
+	}
+	if m := c.Metrics(); m.Reads != 1 || m.Writes != 0 {
+		t.Fatalf("clone started with counters %+v, want fresh ones", m)
+	}
+
+	c.Write(ctx, "from clone")
+	if data, _ := r.Read(ctx); data != "original" {
+		t.Fatalf("write to the clone reached the original: %q", data)
+	}
+	r.Write(ctx, "from original")
+	if data, _ := c.Read(ctx); data != "from clone" {
+		t.Fatalf("write to the original reached the clone: %q", data)
+	}
+}
+
+func TestCloneHasOwnLock(t *testing.T) {
+	r := NewResource("initial")
+	c := r.Clone()
+
+	// The original's write lock is held, but the clone must stay usable
+	_, err := blockedFor(t, r, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		return c.Write(ctx, "unblocked")
+	})
+	if err != nil {
+		t.Fatalf("clone contended with the original's lock: %v", err)
+	}
+}
+
+func TestCloneKeepsConfiguration(t *testing.T) {
+	r := NewResource("initial", WithMaxBytes(10))
+	r.SetReadOnly(true)
+	c := r.Clone()
+
+	if err := c.Write(context.Background(), "x"); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("got %v, want the clone to inherit read-only mode", err)
+	}
+	c.SetReadOnly(false)
+	if !r.ReadOnly() {
+		t.Fatal("changing the clone's mode changed the original's")
+	}
+	if err := c.Write(context.Background(), "far too long for the limit"); !errors.Is(err, ErrMemoryLimit) {
+		t.Fatalf("got %v, want the clone to inherit the memory limit", err)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithWriteCoalescing buffers writes to a key for window and applies only the latest value,
+// so rapid successive writes to the same key take the shard lock once. Reads, deletes, and
+// scans flush any pending write they depend on first, so they never observe stale data.
+func WithWriteCoalescing(window time.Duration) KeyedOption {
+	return func(k *KeyedResource) {
+		k.coalescer = &writeCoalescer{
+			window:  window,
+			pending: make(map[string]*pendingWrite),
+		}
+	}
+}
+
+// writeCoalescer holds writes buffered by a KeyedResource. Its lock is held while a pending
+// write is applied, so flushes of the same key can never be applied out of order.
+type writeCoalescer struct {
+	window time.Duration
+
+	mu        sync.Mutex
+	pending   map[string]*pendingWrite
+	coalesced uint64 // Writes superseded by a later write before being applied
+}
+
+// pendingWrite is the latest buffered value of a key and the timer that will apply it.
+type pendingWrite struct {
+	value string
+	timer *time.Timer
+}
+
+// buffer records value as the pending write for key, scheduling a flush if none is pending.
+func (k *KeyedResource) buffer(key, value string) {
+	c := k.coalescer
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.pending[key]; ok {
+		p.value = value // Supersede the earlier write
+		atomic.AddUint64(&c.coalesced, 1)
+		return
+	}
+	c.pending[key] = &pendingWrite{
+		value: value,
+		timer: time.AfterFunc(c.window, func() { k.flushKey(key) }),
+	}
+}
+
+// flushKey applies the pending write for key, if any.
+func (k *KeyedResource) flushKey(key string) {
+	c := k.coalescer
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k.applyPending(key)
+}
+
+// applyPending applies and clears the pending write for key. c.mu must be held.
+func (k *KeyedResource) applyPending(key string) {
+	c := k.coalescer
+	p, ok := c.pending[key]
+	if !ok {
+		return
+	}
+	p.timer.Stop()
+	delete(c.pending, key)
+	_ = k.write(context.Background(), key, p.value) // Cannot fail without a deadline
+}
+
+// discardPending drops any pending write for key without applying it.
+func (k *KeyedResource) discardPending(key string) {
+	c := k.coalescer
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.pending[key]; ok {
+		p.timer.Stop()
+		delete(c.pending, key)
+	}
+}
+
+// Flush applies every pending coalesced write. Without coalescing it returns immediately.
+func (k *KeyedResource) Flush(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err() // Return error if context is canceled
+	default:
+		if k.coalescer == nil {
+			return nil
+		}
+		c := k.coalescer
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for key := range c.pending {
+			k.applyPending(key)
+		}
+		return nil
+	}
+}
+
+// CoalescedWrites returns the number of writes that were superseded before being applied.
+func (k *KeyedResource) CoalescedWrites() uint64 {
+	if k.coalescer == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&k.coalescer.coalesced)
+}
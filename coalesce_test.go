@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWriteCoalescingTakesLockOnce(t *testing.T) {
+	k := NewKeyedResource(1, WithWriteCoalescing(time.Hour))
+	ctx := context.Background()
+	const writes = 20
+	for i := 0; i < writes; i++ {
+		if err := k.Write(ctx, "counter", fmt.Sprint(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The read forces the buffered write out, so it sees the latest value
+	if value, ok, _ := k.Read(ctx, "counter"); !ok || value != fmt.Sprint(writes-1) {
+		t.Fatalf("got %q, %v, want the last write", value, ok)
+	}
+	if locked := k.ShardMetrics()[0].Writes; locked >= writes {
+		t.Fatalf("shard write-locked %d times for %d writes", locked, writes)
+	}
+	if n := k.CoalescedWrites(); n != writes-1 {
+		t.Fatalf("got %d coalesced writes, want %d", n, writes-1)
+	}
+}
+
+func TestWriteCoalescingFlushesAfterWindow(t *testing.T) {
+	k := NewKeyedResource(1, WithWriteCoalescing(5*time.Millisecond))
+	k.Write(context.Background(), "key", "value")
+
+	deadline := time.Now().Add(time.Second)
+	for k.ShardMetrics()[0].Writes == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("buffered write was never applied")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWriteCoalescingDeleteDiscardsPending(t *testing.T) {
+	k := NewKeyedResource(1, WithWriteCoalescing(time.Hour))
+	ctx := context.Background()
+	k.Write(ctx, "key", "value")
+	k.Delete(ctx, "key")
+	if err := k.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := k.Read(ctx, "key"); ok {
+		t.Fatal("buffered write resurrected a deleted key")
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// lastResourceID is the most recently assigned resource ID.
+var lastResourceID uint64
+
+// CopyFrom atomically replaces the data with src's, reading src under its read lock while
+// holding the receiver's write lock. The two locks are always acquired in order of resource ID,
+// so concurrent copies in opposite directions cannot deadlock.
+func (r *Resource) CopyFrom(ctx context.Context, src *Resource) (err error) {
+	if src == r {
+		return nil // Copying a resource onto itself changes nothing
+	}
+	ctx, cancel := r.withDefaultTimeout(ctx, OpWrite)
+	defer cancel()
+
+	ev := r.startEvent(ctx, OpWrite)
+	defer func() { r.finishEvent(ev, err) }()
+
+	if err := src.checkOpen(); err != nil {
+		return err
+	}
+	if r.id < src.id {
+		if err := r.lockForWrite(ctx, &ev); err != nil {
+			return err
+		}
+		defer r.mu.Unlock()
+		if err := src.mu.RLock(ctx); err != nil { // Acquire the source's read lock
+			return err
+		}
+		defer src.mu.RUnlock()
+	} else {
+		if err := src.mu.RLock(ctx); err != nil { // Acquire the source's read lock
+			return err
+		}
+		defer src.mu.RUnlock()
+		if err := r.lockForWrite(ctx, &ev); err != nil {
+			return err
+		}
+		defer r.mu.Unlock()
+	}
+	ev.Value, err = r.apply(ctx, func(string) (string, error) {
+		return src.data, nil
+	})
+	return err
+}
+
+// nextResourceID returns a new process-wide unique resource ID.
+func nextResourceID() uint64 {
+	return atomic.AddUint64(&lastResourceID, 1)
+}
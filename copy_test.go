@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCopyFrom(t *testing.T) {
+	src, dst := NewResource("source"), NewResource("destination")
+	ctx := context.Background()
+
+	if err := dst.CopyFrom(ctx, src); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := dst.Read(ctx); data != "source" || dst.Version() != 1 {
+		t.Fatalf("destination holds %q at version %d", data, dst.Version())
+	}
+	src.Write(ctx, "later")
+	if data, _ := dst.Read(ctx); data != "source" {
+		t.Fatalf("later write to the source reached the copy: %q", data)
+	}
+}
+
+func TestCopyFromBothDirections(t *testing.T) {
+	a, b := NewResource("a"), NewResource("b")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Copies in opposite directions at once would deadlock without a consistent lock order
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			a.Write(ctx, fmt.Sprint("a", i))
+			if err := a.CopyFrom(ctx, b); err != nil {
+				t.Error(err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			if err := b.CopyFrom(ctx, a); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
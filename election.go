@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// leaderKey is the key under which the current leader's candidate ID is stored.
+const leaderKey = "leader"
+
+// defaultElectionTTL is how long leadership lasts without renewal unless WithElectionTTL is used.
+const defaultElectionTTL = 5 * time.Second
+
+// WithElectionTTL sets how long leadership obtained through Elect lasts without renewal.
+func WithElectionTTL(ttl time.Duration) KeyedOption {
+	return func(k *KeyedResource) {
+		k.electionTTL = ttl
+	}
+}
+
+// Elect attempts to make candidateID the leader by claiming the leader key with a TTL lease.
+// Only one candidate holds leadership at a time. The leader must call renew before the TTL
+// elapses to keep leadership; renew does nothing once leadership has passed to another candidate.
+// If the leader stops renewing, the lease expires and a later Elect by another candidate succeeds.
+// A leader calling Elect again renews its lease and learns whether it still leads. resign gives up
+// leadership immediately. When isLeader is false, renew and resign do nothing.
+func (k *KeyedResource) Elect(ctx context.Context, candidateID string) (isLeader bool, renew func(), resign func()) {
+	ttl := k.electionTTL
+	if ttl <= 0 {
+		ttl = defaultElectionTTL
+	}
+
+	won, err := k.WriteIfAbsent(ctx, leaderKey, candidateID, ttl)
+	if err != nil || !won {
+		// A candidate re-electing itself while still leader keeps leadership
+		if err != nil || !k.refresh(leaderKey, candidateID, ttl) {
+			return false, func() {}, func() {}
+		}
+	}
+
+	renew = func() {
+		k.refresh(leaderKey, candidateID, ttl)
+	}
+	resign = func() {
+		_, _ = k.DeleteIf(context.Background(), leaderKey, candidateID)
+	}
+	return true, renew, resign
+}
+
+// refresh extends the TTL of key if it still holds value, and reports whether it did.
+func (k *KeyedResource) refresh(key, value string, ttl time.Duration) bool {
+	s := k.shard(key)
+	_ = s.mu.Lock(context.Background()) // Cannot fail without a deadline
+	defer s.mu.Unlock()
+	if current, ok := s.get(key); !ok || current != value {
+		return false
+	}
+	s.setTTL(key, ttl)
+	return true
+}
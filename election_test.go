@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestElectExactlyOneLeader(t *testing.T) {
+	k := NewKeyedResource(4, WithElectionTTL(time.Hour))
+	const candidates = 8
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		leaders []string
+	)
+	for i := 0; i < candidates; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			if isLeader, _, _ := k.Elect(context.Background(), id); isLeader {
+				mu.Lock()
+				leaders = append(leaders, id)
+				mu.Unlock()
+			}
+		}(fmt.Sprint("candidate-", i))
+	}
+	wg.Wait()
+	if len(leaders) != 1 {
+		t.Fatalf("got leaders %v, want exactly one", leaders)
+	}
+}
+
+func TestElectFailover(t *testing.T) {
+	const ttl = 20 * time.Millisecond
+	k := NewKeyedResource(4, WithElectionTTL(ttl))
+	ctx := context.Background()
+
+	isLeader, renew, _ := k.Elect(ctx, "a")
+	if !isLeader {
+		t.Fatal("first candidate did not win")
+	}
+	for i := 0; i < 3; i++ {
+		time.Sleep(ttl / 2)
+		renew()
+		if won, _, _ := k.Elect(ctx, "b"); won {
+			t.Fatal("second candidate won while the leader was renewing")
+		}
+	}
+
+	// The leader stops renewing, so its lease expires and the other candidate takes over
+	time.Sleep(2 * ttl)
+	if won, _, _ := k.Elect(ctx, "b"); !won {
+		t.Fatal("leadership did not fail over after the lease expired")
+	}
+	renew()
+	if leader, _, _ := k.Read(ctx, leaderKey); leader != "b" {
+		t.Fatalf("leader is %q after the former leader renewed, want b", leader)
+	}
+	if isLeader, _, _ := k.Elect(ctx, "a"); isLeader {
+		t.Fatal("former leader still reported as leader")
+	}
+}
+
+func TestElectResign(t *testing.T) {
+	k := NewKeyedResource(4, WithElectionTTL(time.Hour))
+	ctx := context.Background()
+	_, _, resign := k.Elect(ctx, "a")
+	resign()
+	if won, _, _ := k.Elect(ctx, "b"); !won {
+		t.Fatal("leadership was not released by resign")
+	}
+}
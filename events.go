@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Operation names recorded in events.
+const (
+	OpRead  = "read"
+	OpWrite = "write"
+)
+
+// Event records a single operation performed on a resource.
+type Event struct {
+	OpID     uint64         // Unique ID of the operation
+	ParentID uint64         // ID of the operation that caused this one; zero if none
+	Worker   WorkerIdentity // Client that issued the operation
+	Op       string         // Kind of operation, such as OpRead or OpWrite
+	Value    string         // Data read or written; empty if the operation failed
+	Start    time.Time      // Time the operation was issued
+	Duration time.Duration  // Time taken including the lock wait
+	LockWait time.Duration  // Portion of Duration spent waiting for the lock
+	Err      error          // Error returned by the operation, if any
+
+	sampled bool        // Whether the operation's latency is recorded in the metrics histograms
+	flight  *inFlightOp // Entry in the resource's in-flight operations until the event finishes
+}
+
+// lastOperationID is the most recently assigned operation ID.
+var lastOperationID uint64
+
+// NewOperationID returns a new process-wide unique operation ID.
+func NewOperationID() uint64 {
+	return atomic.AddUint64(&lastOperationID, 1)
+}
+
+// Context keys for operation causality.
+type (
+	operationIDKey struct{}
+	parentIDKey    struct{}
+)
+
+// WithOperationID returns a copy of ctx that assigns id to the next operation issued with it.
+// This lets a caller know an operation's ID up front, so it can be used as the parent of later operations.
+func WithOperationID(ctx context.Context, id uint64) context.Context {
+	return context.WithValue(ctx, operationIDKey{}, id)
+}
+
+// WithParentOperation returns a copy of ctx whose operations record id as their parent.
+func WithParentOperation(ctx context.Context, id uint64) context.Context {
+	return context.WithValue(ctx, parentIDKey{}, id)
+}
+
+// ParentOperationFromContext returns the parent operation ID carried by ctx, or zero if none.
+func ParentOperationFromContext(ctx context.Context) uint64 {
+	id, _ := ctx.Value(parentIDKey{}).(uint64)
+	return id
+}
+
+// startEvent begins recording an operation issued with ctx.
+func (r *Resource) startEvent(ctx context.Context, op string) Event {
+	id, ok := ctx.Value(operationIDKey{}).(uint64)
+	if !ok {
+		id = NewOperationID()
+	}
+	worker, _ := IdentityFromContext(ctx)
+	ev := Event{
+		OpID:     id,
+		ParentID: ParentOperationFromContext(ctx),
+		Worker:   worker,
+		Op:       op,
+		Start:    time.Now(),
+		sampled:  r.metrics.sampler.sample(),
+	}
+	r.track(&ev)
+	return ev
+}
+
+// WithEventTimeline records every completed operation in a timeline returned by Events.
+// The timeline grows with every operation, so it is meant for finite runs such as simulations;
+// a long-lived resource should use WithOperationLog, which keeps only the most recent operations.
+func WithEventTimeline() ResourceOption {
+	return func(r *Resource) {
+		r.timeline = 1
+	}
+}
+
+// recordTimeline enables the event timeline on a resource that was created without WithEventTimeline.
+func (r *Resource) recordTimeline() {
+	atomic.StoreInt32(&r.timeline, 1)
+}
+
+// finishEvent completes ev with the operation's outcome and appends it to the timeline, if enabled.
+func (r *Resource) finishEvent(ev Event, err error) {
+	r.untrack(ev)
+	ev.flight = nil
+	ev.Duration = time.Since(ev.Start)
+	ev.Err = err
+	r.metrics.record(ev.Op, err)
+	if ev.Op == OpRead && ev.sampled {
+		r.metrics.readLatency.observe(ev.Duration)
+	}
+	r.logSlow(ev)
+	r.streamEvent(ev)
+	if r.opLog != nil {
+		r.opLog.add(ev)
+	}
+	if atomic.LoadInt32(&r.timeline) == 0 {
+		return
+	}
+
+	r.eventsMu.Lock()
+	defer r.eventsMu.Unlock()
+	r.events = append(r.events, ev)
+	atomic.AddInt64(&r.eventsBytes, int64(len(ev.Value)))
+}
+
+// Events returns the timeline of operations performed on the resource in completion order.
+// It is empty unless the resource records a timeline, as enabled by WithEventTimeline.
+func (r *Resource) Events() []Event {
+	r.eventsMu.Lock()
+	defer r.eventsMu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+// collectEvents merges the timelines of several resources, ordered by start time.
+func collectEvents(resources []*Resource) []Event {
+	var events []Event
+	for _, r := range resources {
+		events = append(events, r.Events()...)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Start.Before(events[j].Start)
+	})
+	return events
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEventsRecordParentOperation(t *testing.T) {
+	r := NewResource("initial", WithEventTimeline())
+	ctx := context.Background()
+
+	parent := NewOperationID()
+	if _, err := r.Read(WithOperationID(ctx, parent)); err != nil {
+		t.Fatal(err)
+	}
+	child := WithParentOperation(ctx, parent)
+	if err := r.Write(child, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Write(child, "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	events := r.Events()
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+	if events[0].OpID != parent || events[0].ParentID != 0 {
+		t.Fatalf("parent event: got op %d parent %d, want op %d and no parent", events[0].OpID, events[0].ParentID, parent)
+	}
+	for _, ev := range events[1:] {
+		if ev.ParentID != parent {
+			t.Errorf("%s %q: got parent %d, want %d", ev.Op, ev.Value, ev.ParentID, parent)
+		}
+		if ev.OpID == parent {
+			t.Errorf("%s %q reused the parent's operation ID", ev.Op, ev.Value)
+		}
+	}
+}
+
+func TestEventsTimelineIsOptIn(t *testing.T) {
+	r := NewResource("initial")
+	for i := 0; i < 100; i++ {
+		if err := r.Write(context.Background(), "value"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if events := r.Events(); len(events) != 0 {
+		t.Fatalf("got %d events without WithEventTimeline, want none", len(events))
+	}
+}
+
+func TestSimulationRecordsTimelineForFactoryResources(t *testing.T) {
+	factory := func(data string) *Resource { return NewResource(data) }
+	result, err := RunSimulation(2, 5*time.Second, WithResourceFactory(factory))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(result.Events), result.Reads+result.Writes; got != want {
+		t.Fatalf("got %d events, want one per counted operation (%d)", got, want)
+	}
+}
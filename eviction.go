@@ -0,0 +1,106 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// EvictionPolicy selects which entry a size-capped KeyedResource removes when it is full.
+type EvictionPolicy int
+
+const (
+	// EvictLRU removes the least recently read or written key.
+	EvictLRU EvictionPolicy = iota
+	// EvictFIFO removes the earliest inserted key, regardless of later access.
+	EvictFIFO
+)
+
+// WithMaxKeys caps the number of keys held by the store. Writes that add a key beyond the cap
+// evict entries according to policy.
+func WithMaxKeys(max int, policy EvictionPolicy) KeyedOption {
+	return func(k *KeyedResource) {
+		k.order = &keyOrder{
+			max:    max,
+			policy: policy,
+			list:   list.New(),
+			elems:  make(map[string]*list.Element),
+		}
+	}
+}
+
+// keyOrder tracks key recency or insertion order across all shards for eviction.
+// Its lock is only ever taken while holding at most one shard lock, never the other way around.
+type keyOrder struct {
+	max    int
+	policy EvictionPolicy
+
+	mu    sync.Mutex
+	list  *list.List               // Keys from most to least recently used or inserted
+	elems map[string]*list.Element // Position of each key in list
+}
+
+// touch records a read of key, refreshing its recency under LRU.
+func (o *keyOrder) touch(key string) {
+	if o.policy != EvictLRU {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if e, ok := o.elems[key]; ok {
+		o.list.MoveToFront(e)
+	}
+}
+
+// insert records a write of key and returns the keys that must be evicted to respect the cap.
+func (o *keyOrder) insert(key string) []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if e, ok := o.elems[key]; ok {
+		if o.policy == EvictLRU {
+			o.list.MoveToFront(e)
+		}
+		return nil
+	}
+	o.elems[key] = o.list.PushFront(key)
+
+	var victims []string
+	for o.max > 0 && o.list.Len() > o.max {
+		oldest := o.list.Back()
+		victim := o.list.Remove(oldest).(string)
+		delete(o.elems, victim)
+		victims = append(victims, victim)
+	}
+	return victims
+}
+
+// remove forgets key after it was deleted.
+func (o *keyOrder) remove(key string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if e, ok := o.elems[key]; ok {
+		o.list.Remove(e)
+		delete(o.elems, key)
+	}
+}
+
+// contains reports whether key is currently tracked.
+func (o *keyOrder) contains(key string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	_, ok := o.elems[key]
+	return ok
+}
+
+// evict deletes victims from their shards, skipping any key that was written again since it was chosen.
+// It must be called without holding any shard lock.
+func (k *KeyedResource) evict(victims []string) {
+	for _, key := range victims {
+		s := k.shard(key)
+		_ = s.mu.Lock(context.Background()) // Cannot fail without a deadline
+		if !k.order.contains(key) {
+			s.remove(key)
+		}
+		s.mu.Unlock()
+	}
+}
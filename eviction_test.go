@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEvictionPolicies(t *testing.T) {
+	tests := []struct {
+		policy  EvictionPolicy
+		evicted string
+	}{
+		{EvictLRU, "b"},  // "a" was read after "b" was written, so "b" is least recently used
+		{EvictFIFO, "a"}, // "a" was inserted first; the read does not save it
+	}
+	for _, tt := range tests {
+		k := NewKeyedResource(4, WithMaxKeys(2, tt.policy))
+		ctx := context.Background()
+		k.Write(ctx, "a", "1")
+		k.Write(ctx, "b", "2")
+		k.Read(ctx, "a")
+		k.Write(ctx, "c", "3") // Over the cap
+
+		for _, key := range []string{"a", "b", "c"} {
+			_, ok, _ := k.Read(ctx, key)
+			if want := key != tt.evicted; ok != want {
+				t.Errorf("policy %d: key %q present %v, want %v", tt.policy, key, ok, want)
+			}
+		}
+	}
+}
+
+func TestEvictionRewriteDoesNotGrow(t *testing.T) {
+	k := NewKeyedResource(4, WithMaxKeys(2, EvictFIFO))
+	ctx := context.Background()
+	k.Write(ctx, "a", "1")
+	k.Write(ctx, "b", "2")
+	k.Write(ctx, "a", "updated") // Existing key, so nothing is evicted
+
+	for key, want := range map[string]string{"a": "updated", "b": "2"} {
+		if value, ok, _ := k.Read(ctx, key); !ok || value != want {
+			t.Errorf("key %q: got %q, %v", key, value, ok)
+		}
+	}
+}
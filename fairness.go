@@ -0,0 +1,77 @@
+package main
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// LockFairness summarizes how evenly operations were served by the lock.
+type LockFairness struct {
+	Waits         uint64        // Lock acquisitions measured
+	MeanWait      time.Duration // Average time spent waiting for the lock
+	WaitStdDev    time.Duration // Standard deviation of lock waits; high values mean some operations wait far longer than others
+	MaxReadWait   time.Duration // Longest wait of any read
+	MaxWriteWait  time.Duration // Longest wait of any write
+	StarvedWrites uint64        // Writes that waited longer than the starvation threshold
+}
+
+// waitStats accumulates lock-wait measurements for LockFairness. It is updated on every lock
+// acquisition, so it uses sharded atomics rather than a shared lock.
+type waitStats struct {
+	count             shardedCounter
+	sum               shardedCounter // In nanoseconds, for the mean
+	sumSquares        shardedFloat   // In seconds squared, for the variance
+	maxRead, maxWrite int64          // In nanoseconds, raised with compare-and-swap
+}
+
+// record adds the lock wait of an operation of kind op.
+func (s *waitStats) record(op string, wait time.Duration) {
+	s.count.Add(1)
+	s.sum.Add(uint64(wait))
+	secs := wait.Seconds()
+	s.sumSquares.Add(secs * secs)
+	if op == OpRead {
+		raiseMax(&s.maxRead, int64(wait))
+	} else if op == OpWrite {
+		raiseMax(&s.maxWrite, int64(wait))
+	}
+}
+
+// raiseMax stores v in *max if it is larger than the current value.
+func raiseMax(max *int64, v int64) {
+	for {
+		old := atomic.LoadInt64(max)
+		if v <= old || atomic.CompareAndSwapInt64(max, old, v) {
+			return
+		}
+	}
+}
+
+// endLockWait returns how long the operation waited for the lock and records it for the fairness metrics.
+func (r *Resource) endLockWait(w lockWait) time.Duration {
+	wait := time.Since(w.start)
+	r.metrics.waits.record(w.op, wait)
+	return wait
+}
+
+// fairness returns a snapshot of the lock fairness metrics.
+// Waits recorded while the snapshot is taken may be partly included.
+func (r *Resource) fairness() LockFairness {
+	s := &r.metrics.waits
+	count := s.count.Load()
+	f := LockFairness{
+		Waits:         count,
+		MaxReadWait:   time.Duration(atomic.LoadInt64(&s.maxRead)),
+		MaxWriteWait:  time.Duration(atomic.LoadInt64(&s.maxWrite)),
+		StarvedWrites: atomic.LoadUint64(&r.starvedWrites),
+	}
+	if count == 0 {
+		return f
+	}
+	mean := time.Duration(s.sum.Load()).Seconds() / float64(count)
+	variance := math.Max(s.sumSquares.Load()/float64(count)-mean*mean, 0) // Clamp rounding error
+	f.MeanWait = time.Duration(mean * float64(time.Second))
+	f.WaitStdDev = time.Duration(math.Sqrt(variance) * float64(time.Second))
+	return f
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFairnessCountsStarvedWritersUnderReadLoad(t *testing.T) {
+	const threshold = 5 * time.Millisecond
+	r := NewResource("initial", WithStarvationThreshold(threshold))
+	ctx := context.Background()
+
+	// Keep readers holding the lock for a while, then issue a write behind them
+	var wg sync.WaitGroup
+	held := make(chan struct{}, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.WithReadLock(ctx, func(string) error {
+				held <- struct{}{}
+				time.Sleep(4 * threshold)
+				return nil
+			})
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		<-held
+	}
+	if err := r.Write(ctx, "late"); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	f := r.Metrics().Fairness
+	if f.StarvedWrites != 1 {
+		t.Fatalf("got %d starved writes, want 1", f.StarvedWrites)
+	}
+	if f.MaxWriteWait < threshold {
+		t.Fatalf("longest write wait %v, want at least the %v threshold", f.MaxWriteWait, threshold)
+	}
+	if f.Waits != 5 {
+		t.Fatalf("measured %d lock waits, want 5", f.Waits)
+	}
+}
+
+func TestFairnessMeanAndStdDev(t *testing.T) {
+	r := NewResource("")
+	for _, wait := range []time.Duration{10 * time.Millisecond, 30 * time.Millisecond} {
+		r.metrics.waits.record(OpWrite, wait)
+	}
+	r.metrics.waits.record(OpRead, 20*time.Millisecond)
+
+	f := r.fairness()
+	if f.MeanWait != 20*time.Millisecond {
+		t.Fatalf("mean %v, want 20ms", f.MeanWait)
+	}
+	if d := f.WaitStdDev - 8164965*time.Nanosecond; d < -time.Microsecond || d > time.Microsecond {
+		t.Fatalf("standard deviation %v, want about 8.165ms", f.WaitStdDev)
+	}
+	if f.MaxReadWait != 20*time.Millisecond || f.MaxWriteWait != 30*time.Millisecond {
+		t.Fatalf("got max waits %v and %v", f.MaxReadWait, f.MaxWriteWait)
+	}
+}
+
+func BenchmarkWaitStatsRecord(b *testing.B) {
+	var s waitStats
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.record(OpRead, time.Microsecond)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrInjectedFault is the synthetic error returned by operations failed by a FaultInjector.
+var ErrInjectedFault = errors.New("injected fault")
+
+// FaultInjector makes a fraction of worker operations fail or slow down, for resilience testing.
+// Decisions are drawn from Rand, so a seeded source makes a run's faults deterministic.
+type FaultInjector struct {
+	FailureRate float64       // Fraction of operations that fail with ErrInjectedFault
+	LatencyRate float64       // Fraction of operations delayed by Latency before running
+	Latency     time.Duration // Delay added to slowed operations
+	Rand        *rand.Rand    // Source of fault decisions; nil uses the global source
+
+	mu sync.Mutex // Guards Rand, which is not safe for concurrent use
+}
+
+// WithFaultInjection passes every worker operation attempt through f, so retries see fresh draws.
+func WithFaultInjection(f *FaultInjector) SimulationOption {
+	return func(c *simulationConfig) {
+		c.faults = f
+	}
+}
+
+// draw returns a random number in [0, 1).
+func (f *FaultInjector) draw() float64 {
+	if f.Rand == nil {
+		return rand.Float64()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.Rand.Float64()
+}
+
+// run runs op unless a fault is injected, first delaying it if it is chosen to be slowed.
+// A nil injector runs op unchanged.
+func (f *FaultInjector) run(ctx context.Context, op func() error) error {
+	if f == nil {
+		return op()
+	}
+	if f.draw() < f.FailureRate {
+		return ErrInjectedFault
+	}
+	if f.Latency > 0 && f.draw() < f.LatencyRate {
+		select {
+		case <-time.After(f.Latency):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return op()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestFaultInjectorFailureRate(t *testing.T) {
+	f := &FaultInjector{FailureRate: 0.5, Rand: rand.New(rand.NewSource(1))}
+	const ops = 1000
+	failed := 0
+	for i := 0; i < ops; i++ {
+		if err := f.run(context.Background(), func() error { return nil }); errors.Is(err, ErrInjectedFault) {
+			failed++
+		}
+	}
+	if failed < ops*4/10 || failed > ops*6/10 {
+		t.Fatalf("%d of %d operations failed, want roughly half", failed, ops)
+	}
+}
+
+func TestFaultInjectorDeterministic(t *testing.T) {
+	outcomes := func() []bool {
+		f := &FaultInjector{FailureRate: 0.5, Rand: rand.New(rand.NewSource(42))}
+		var out []bool
+		for i := 0; i < 50; i++ {
+			out = append(out, f.run(context.Background(), func() error { return nil }) == nil)
+		}
+		return out
+	}
+	a, b := outcomes(), outcomes()
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("outcome %d differs between runs with the same seed", i)
+		}
+	}
+}
+
+func TestFaultInjectorLatency(t *testing.T) {
+	const latency = 20 * time.Millisecond
+	f := &FaultInjector{LatencyRate: 1, Latency: latency}
+	start := time.Now()
+	if err := f.run(context.Background(), func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < latency {
+		t.Fatalf("operation took %v, want it delayed by %v", elapsed, latency)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	f.Latency = time.Hour
+	if err := f.run(ctx, func() error { return nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the delay cut short by the context", err)
+	}
+}
+
+func TestSimulationWithHalfFailing(t *testing.T) {
+	const workers = 20
+	result, _ := RunSimulation(workers, 5*time.Second,
+		WithFaultInjection(&FaultInjector{FailureRate: 0.5, Rand: rand.New(rand.NewSource(7))}),
+		WithRetryBudget(1000),
+		WithAttemptDeadline(time.Second, 2), // Each operation gets one retry
+	)
+	if result.Retries == 0 {
+		t.Fatal("no retries at a 50% failure rate")
+	}
+	// With one retry, an operation fails only if both attempts do, about a quarter of the time
+	ops := 2 * workers
+	if result.Failures > ops/2 {
+		t.Fatalf("%d of %d operations failed after retrying, want roughly a quarter", result.Failures, ops)
+	}
+	if result.Reads+result.Writes+result.Failures != ops {
+		t.Fatalf("got %d reads, %d writes and %d failures, want %d operations in all",
+			result.Reads, result.Writes, result.Failures, ops)
+	}
+}
@@ -1 +1,3 @@
-module authServer
\ No newline at end of file
+module authServer
+
+go 1.21
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// histogramBuckets is the number of finite buckets; bucket i holds durations up to 1µs << i.
+const histogramBuckets = 25
+
+// histogram is a latency histogram with exponentially sized buckets, safe for concurrent use.
+type histogram struct {
+	counts [histogramBuckets + 1]uint64 // The last bucket holds durations beyond the largest bound
+	count  uint64
+	sum    int64 // Total of all observed durations, in nanoseconds
+}
+
+// observe records one duration.
+func (h *histogram) observe(d time.Duration) {
+	i := 0
+	for i < histogramBuckets && d > histogramBound(i) {
+		i++
+	}
+	atomic.AddUint64(&h.counts[i], 1)
+	atomic.AddUint64(&h.count, 1)
+	atomic.AddInt64(&h.sum, int64(d))
+}
+
+// histogramBound returns the upper bound of bucket i.
+func histogramBound(i int) time.Duration {
+	return time.Microsecond << uint(i)
+}
+
+// snapshot returns the current contents of the histogram.
+func (h *histogram) snapshot() Histogram {
+	s := Histogram{
+		Count:   atomic.LoadUint64(&h.count),
+		Sum:     time.Duration(atomic.LoadInt64(&h.sum)),
+		Buckets: make([]HistogramBucket, 0, len(h.counts)),
+	}
+	for i := range h.counts {
+		bound := time.Duration(-1) // Overflow bucket has no upper bound
+		if i < histogramBuckets {
+			bound = histogramBound(i)
+		}
+		s.Buckets = append(s.Buckets, HistogramBucket{UpperBound: bound, Count: atomic.LoadUint64(&h.counts[i])})
+	}
+	return s
+}
+
+// Histogram is a snapshot of a latency distribution.
+type Histogram struct {
+	Count   uint64            // Number of observations
+	Sum     time.Duration     // Total of all observations
+	Buckets []HistogramBucket // Observation counts by upper bound, in increasing order
+}
+
+// HistogramBucket counts the observations no larger than UpperBound.
+// The last bucket has an UpperBound of -1 and holds everything beyond the previous bound.
+type HistogramBucket struct {
+	UpperBound time.Duration
+	Count      uint64
+}
+
+// Mean returns the average observation, or zero if there are none.
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Merge returns the combined distribution of h and other, which must use the same buckets.
+func (h Histogram) Merge(other Histogram) Histogram {
+	m := Histogram{
+		Count:   h.Count + other.Count,
+		Sum:     h.Sum + other.Sum,
+		Buckets: make([]HistogramBucket, max(len(h.Buckets), len(other.Buckets))),
+	}
+	for i := range m.Buckets {
+		if i < len(h.Buckets) {
+			m.Buckets[i] = h.Buckets[i]
+		} else {
+			m.Buckets[i].UpperBound = other.Buckets[i].UpperBound
+		}
+		if i < len(other.Buckets) {
+			m.Buckets[i].Count += other.Buckets[i].Count
+		}
+	}
+	return m
+}
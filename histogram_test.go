@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHistogramBuckets(t *testing.T) {
+	var h histogram
+	h.observe(500 * time.Nanosecond) // Bucket 0: up to 1µs
+	h.observe(3 * time.Microsecond)  // Bucket 2: up to 4µs
+	h.observe(time.Hour)             // Beyond the largest bound
+
+	s := h.snapshot()
+	if s.Count != 3 || s.Sum != time.Hour+3500*time.Nanosecond {
+		t.Fatalf("got count %d and sum %v", s.Count, s.Sum)
+	}
+	for i, want := range map[int]uint64{0: 1, 1: 0, 2: 1, histogramBuckets: 1} {
+		if got := s.Buckets[i].Count; got != want {
+			t.Errorf("bucket %d: got %d, want %d", i, got, want)
+		}
+	}
+	if last := s.Buckets[len(s.Buckets)-1]; last.UpperBound != -1 {
+		t.Errorf("overflow bucket bound %v, want -1", last.UpperBound)
+	}
+
+	merged := s.Merge(s)
+	if merged.Count != 6 || merged.Buckets[2].Count != 2 || merged.Mean() != s.Mean() {
+		t.Fatalf("merged histogram %+v", merged)
+	}
+}
+
+func TestReadQueueWaitUnderContention(t *testing.T) {
+	const hold = 20 * time.Millisecond
+	r := NewResource("initial")
+	ctx := context.Background()
+	r.Read(ctx) // Uncontended, so it barely waits
+
+	tx, err := r.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(hold)
+		tx.Rollback()
+	}()
+	if _, err := r.Read(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	m := r.Metrics()
+	if m.ReadQueueWait.Count != 2 || m.ReadLatency.Count != 2 {
+		t.Fatalf("got %d queue waits and %d latencies, want 2 each", m.ReadQueueWait.Count, m.ReadLatency.Count)
+	}
+	if m.ReadQueueWait.Sum < hold {
+		t.Fatalf("queue wait total %v, want at least the %v the lock was held", m.ReadQueueWait.Sum, hold)
+	}
+	if m.ReadLatency.Sum < m.ReadQueueWait.Sum {
+		t.Fatalf("read latency %v is less than its queue wait %v", m.ReadLatency.Sum, m.ReadQueueWait.Sum)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Media types supported by the HTTP API.
+const (
+	mediaJSON = "application/json"
+	mediaText = "text/plain"
+)
+
+// maxBodyBytes bounds the size of a PUT request body.
+const maxBodyBytes = 1 << 20
+
+// TimeoutHeader lets a client request a shorter operation deadline, as a duration such as "250ms".
+const TimeoutHeader = "X-Request-Timeout"
+
+// defaultOperationTimeout bounds each operation unless WithOperationTimeout is used.
+const defaultOperationTimeout = 5 * time.Second
+
+// Handler exposes a Resource over HTTP at /resource.
+// GET returns the data and PUT replaces it, as JSON ({"data": "..."}) or plain text.
+// GET responses carry an ETag of the resource version, which PUT honors in If-Match.
+type Handler struct {
+	resource  *Resource
+	opTimeout time.Duration // Server maximum for each operation's deadline
+}
+
+// HandlerOption configures optional behavior of a Handler.
+type HandlerOption func(*Handler)
+
+// WithOperationTimeout sets the server's maximum deadline for each operation.
+// Clients may ask for a shorter one with TimeoutHeader but never a longer one.
+func WithOperationTimeout(timeout time.Duration) HandlerOption {
+	return func(h *Handler) {
+		h.opTimeout = timeout
+	}
+}
+
+// NewHandler creates a new Handler serving resource.
+func NewHandler(resource *Resource, opts ...HandlerOption) *Handler {
+	h := &Handler{resource: resource, opTimeout: defaultOperationTimeout}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// resourceBody is the JSON representation of the resource.
+type resourceBody struct {
+	Data string `json:"data"`
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/resource" {
+		http.NotFound(w, req)
+		return
+	}
+	ctx, cancel, err := h.operationContext(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	switch req.Method {
+	case http.MethodGet:
+		h.get(w, req)
+	case http.MethodPut:
+		h.put(w, req)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// operationContext derives the request's operation deadline: the server maximum,
+// shortened to the client's TimeoutHeader if that is smaller.
+func (h *Handler) operationContext(req *http.Request) (context.Context, context.CancelFunc, error) {
+	timeout := h.opTimeout
+	if v := req.Header.Get(TimeoutHeader); v != "" {
+		requested, err := time.ParseDuration(v)
+		if err != nil || requested <= 0 {
+			return nil, nil, fmt.Errorf("invalid %s header %q", TimeoutHeader, v)
+		}
+		if timeout <= 0 || requested < timeout {
+			timeout = requested
+		}
+	}
+	if timeout <= 0 {
+		ctx, cancel := context.WithCancel(req.Context())
+		return ctx, cancel, nil
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	return ctx, cancel, nil
+}
+
+// get writes the data in the representation preferred by the Accept header.
+func (h *Handler) get(w http.ResponseWriter, req *http.Request) {
+	mediaType, ok := negotiate(req.Header.Get("Accept"))
+	if !ok {
+		http.Error(w, "supported types: "+mediaJSON+", "+mediaText, http.StatusNotAcceptable)
+		return
+	}
+	var (
+		data    string
+		version uint64
+	)
+	err := h.resource.WithReadLock(req.Context(), func(d string) error {
+		data, version = d, h.resource.Version() // The version cannot change while the read lock is held
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	w.Header().Set("ETag", etag(version))
+	w.Header().Set("Content-Type", mediaType+"; charset=utf-8")
+	w.Header().Add("Vary", "Accept")
+	if mediaType == mediaJSON {
+		json.NewEncoder(w).Encode(resourceBody{Data: data})
+		return
+	}
+	io.WriteString(w, data)
+}
+
+// put replaces the data with the request body, decoded according to its Content-Type.
+// With an If-Match header, the write only happens if the current version matches one of its ETags.
+func (h *Handler) put(w http.ResponseWriter, req *http.Request) {
+	data, err := decodeBody(w, req)
+	if err != nil {
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+	ifMatch := req.Header.Get("If-Match")
+	err = h.resource.update(req.Context(), func(string) (string, error) {
+		if ifMatch != "" && !etagMatches(ifMatch, h.resource.Version()) {
+			return "", errPreconditionFailed // Checked under the write lock, so no write can slip in between
+		}
+		return data, nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// errPreconditionFailed is returned when a PUT's If-Match header does not match the current version.
+var errPreconditionFailed = errors.New("resource has changed since the given ETag")
+
+// etag returns the strong entity tag for a version of the resource.
+func etag(version uint64) string {
+	return `"v` + strconv.FormatUint(version, 10) + `"`
+}
+
+// etagMatches reports whether an If-Match header matches version. Weak tags never match.
+func etagMatches(ifMatch string, version uint64) bool {
+	want := etag(version)
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == want {
+			return true
+		}
+	}
+	return false
+}
+
+// errUnsupportedMediaType is returned when a PUT body has an unsupported Content-Type.
+var errUnsupportedMediaType = errors.New("unsupported content type")
+
+// errBadBody is returned when a PUT body cannot be decoded.
+var errBadBody = errors.New("malformed request body")
+
+// decodeBody reads the new data from a PUT request. A missing Content-Type is treated as plain text.
+func decodeBody(w http.ResponseWriter, req *http.Request) (string, error) {
+	mediaType := mediaText
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		parsed, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return "", errUnsupportedMediaType
+		}
+		mediaType = parsed
+	}
+
+	body := http.MaxBytesReader(w, req.Body, maxBodyBytes)
+	switch mediaType {
+	case mediaJSON:
+		var b resourceBody
+		if err := json.NewDecoder(body).Decode(&b); err != nil {
+			return "", errBadBody
+		}
+		return b.Data, nil
+	case mediaText:
+		raw, err := io.ReadAll(body)
+		if err != nil {
+			return "", errBadBody
+		}
+		return string(raw), nil
+	default:
+		return "", errUnsupportedMediaType
+	}
+}
+
+// statusFor maps an operation error to an HTTP status code.
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, errUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, errBadBody):
+		return http.StatusBadRequest
+	case errors.Is(err, errPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, ErrMemoryLimit):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrLeased):
+		return http.StatusConflict
+	case errors.Is(err, ErrReadOnly), errors.Is(err, ErrClosed):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusRequestTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// negotiate picks the supported media type best matching an Accept header.
+// An empty header accepts anything, in which case JSON is returned.
+func negotiate(accept string) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return mediaJSON, true
+	}
+
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	for _, r := range ranges {
+		switch r.mediaType {
+		case mediaJSON, "application/*", "*/*":
+			return mediaJSON, true
+		case mediaText, "text/*":
+			return mediaText, true
+		}
+	}
+	return "", false
+}
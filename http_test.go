@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func FuzzDecodeBody(f *testing.F) {
+	for _, seed := range []struct {
+		contentType string
+		body        string
+	}{
+		{mediaJSON, `{"data": "hello"}`},
+		{mediaJSON, `{"data": "é\n\"quoted\""}`},
+		{mediaJSON, `{"data": 42}`},
+		{mediaJSON, `{"data": "unterminated`},
+		{mediaJSON, `[]`},
+		{mediaJSON + "; charset=utf-8", `{}`},
+		{mediaText, "plain\x00bytes\xff"},
+		{"", "no content type"},
+		{"text/html", "<p>unsupported</p>"},
+		{"not a media type;;", "x"},
+	} {
+		f.Add(seed.contentType, []byte(seed.body))
+	}
+
+	f.Fuzz(func(t *testing.T, contentType string, body []byte) {
+		req := httptest.NewRequest(http.MethodPut, "/resource", bytes.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		data, err := decodeBody(httptest.NewRecorder(), req)
+		if err != nil {
+			if !errors.Is(err, errBadBody) && !errors.Is(err, errUnsupportedMediaType) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			return
+		}
+
+		// Whatever was decoded must survive a round trip through the resource and the JSON encoding
+		r := NewResource("")
+		if err := r.Write(context.Background(), data); err != nil {
+			t.Fatal(err)
+		}
+		stored, err := r.Read(context.Background())
+		if err != nil || stored != data {
+			t.Fatalf("stored %q (%v), want %q", stored, err, data)
+		}
+		encoded, err := json.Marshal(resourceBody{Data: stored})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded resourceBody
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if string([]rune(data)) != decoded.Data { // Invalid UTF-8 is encoded as replacement characters
+			t.Fatalf("JSON round trip changed %q into %q", data, decoded.Data)
+		}
+	})
+}
+
+// serve sends a request to h and returns the recorded response.
+func serve(h http.Handler, method, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/resource", strings.NewReader(body))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandlerContentNegotiation(t *testing.T) {
+	tests := []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{mediaJSON, mediaJSON, "{\"data\":\"hello\"}\n"},
+		{mediaText, mediaText, "hello"},
+		{"text/html;q=0.9, text/plain;q=0.5", mediaText, "hello"},
+		{"", mediaJSON, "{\"data\":\"hello\"}\n"},
+	}
+	h := NewHandler(NewResource("hello"))
+	for _, tt := range tests {
+		rec := serve(h, http.MethodGet, "", map[string]string{"Accept": tt.accept})
+		if rec.Code != http.StatusOK {
+			t.Errorf("Accept %q: status %d", tt.accept, rec.Code)
+			continue
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.contentType) {
+			t.Errorf("Accept %q: Content-Type %q, want %s", tt.accept, ct, tt.contentType)
+		}
+		if got := rec.Body.String(); got != tt.body {
+			t.Errorf("Accept %q: body %q, want %q", tt.accept, got, tt.body)
+		}
+	}
+}
+
+func TestHandlerNotAcceptable(t *testing.T) {
+	h := NewHandler(NewResource("hello"))
+	if rec := serve(h, http.MethodGet, "", map[string]string{"Accept": "image/png"}); rec.Code != http.StatusNotAcceptable {
+		t.Fatalf("got status %d, want 406", rec.Code)
+	}
+}
+
+func TestHandlerPutContentTypes(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		status      int
+		want        string
+	}{
+		{mediaJSON, `{"data": "from json"}`, http.StatusNoContent, "from json"},
+		{mediaText + "; charset=utf-8", "from text", http.StatusNoContent, "from text"},
+		{mediaJSON, `{"data": `, http.StatusBadRequest, "initial"},
+		{"text/html", "<p>no</p>", http.StatusUnsupportedMediaType, "initial"},
+	}
+	for _, tt := range tests {
+		r := NewResource("initial")
+		rec := serve(NewHandler(r), http.MethodPut, tt.body, map[string]string{"Content-Type": tt.contentType})
+		if rec.Code != tt.status {
+			t.Errorf("Content-Type %q: status %d, want %d", tt.contentType, rec.Code, tt.status)
+		}
+		if data, _ := r.Read(context.Background()); data != tt.want {
+			t.Errorf("Content-Type %q: stored %q, want %q", tt.contentType, data, tt.want)
+		}
+	}
+}
+
+// lockedHandler returns a handler over a resource whose write lock is held until the test ends.
+func lockedHandler(t *testing.T, opts ...HandlerOption) *Handler {
+	r := NewResource("initial")
+	tx, err := r.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	return NewHandler(r, opts...)
+}
+
+func TestHandlerTimeoutHeader(t *testing.T) {
+	h := lockedHandler(t, WithOperationTimeout(time.Hour))
+	start := time.Now()
+	rec := serve(h, http.MethodGet, "", map[string]string{TimeoutHeader: "20ms"})
+	if rec.Code != http.StatusRequestTimeout {
+		t.Fatalf("got status %d, want 408", rec.Code)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("request took %v, want the client's 20ms deadline", elapsed)
+	}
+}
+
+func TestHandlerTimeoutCappedByServer(t *testing.T) {
+	h := lockedHandler(t, WithOperationTimeout(20*time.Millisecond))
+	start := time.Now()
+	rec := serve(h, http.MethodPut, "x", map[string]string{TimeoutHeader: "1h"})
+	if rec.Code != http.StatusRequestTimeout {
+		t.Fatalf("got status %d, want 408", rec.Code)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("request took %v, want the server's 20ms cap", elapsed)
+	}
+}
+
+func TestHandlerInvalidTimeoutHeader(t *testing.T) {
+	h := NewHandler(NewResource("initial"))
+	for _, v := range []string{"soon", "-1s", "0"} {
+		if rec := serve(h, http.MethodGet, "", map[string]string{TimeoutHeader: v}); rec.Code != http.StatusBadRequest {
+			t.Errorf("header %q: got status %d, want 400", v, rec.Code)
+		}
+	}
+}
+
+func TestHandlerConditionalPut(t *testing.T) {
+	r := NewResource("initial")
+	h := NewHandler(r)
+	tag := serve(h, http.MethodGet, "", nil).Header().Get("ETag")
+	if tag == "" {
+		t.Fatal("GET response has no ETag")
+	}
+
+	if rec := serve(h, http.MethodPut, "first", map[string]string{"Content-Type": mediaText, "If-Match": tag}); rec.Code != http.StatusNoContent {
+		t.Fatalf("PUT with the current ETag: status %d, want 204", rec.Code)
+	}
+	// The first PUT bumped the version, so the same tag is now stale
+	if rec := serve(h, http.MethodPut, "second", map[string]string{"Content-Type": mediaText, "If-Match": tag}); rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("PUT with a stale ETag: status %d, want 412", rec.Code)
+	}
+	if data, _ := r.Read(context.Background()); data != "first" {
+		t.Fatalf("stored %q, want the stale PUT to be rejected", data)
+	}
+
+	current := func() string { return serve(h, http.MethodGet, "", nil).Header().Get("ETag") }
+	for _, ifMatch := range []func() string{
+		func() string { return "*" },
+		func() string { return `"v0", ` + current() },
+	} {
+		ifMatch := ifMatch()
+		if rec := serve(h, http.MethodPut, "x", map[string]string{"Content-Type": mediaText, "If-Match": ifMatch}); rec.Code != http.StatusNoContent {
+			t.Errorf("If-Match %q: status %d, want 204", ifMatch, rec.Code)
+		}
+	}
+	if rec := serve(h, http.MethodPut, "x", map[string]string{"Content-Type": mediaText, "If-Match": "W/" + current()}); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("weak ETag: status %d, want 412", rec.Code)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// idempotencyKey is the context key for a write's idempotency key.
+type idempotencyKey struct{}
+
+// WithIdempotencyKey returns a copy of ctx whose writes carry key. On a resource configured with
+// WithIdempotencyWindow, a write repeating a key that succeeded within the window is not applied again.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// WithIdempotencyWindow remembers the idempotency keys of successful writes for window, so that
+// a retried write with the same key is a no-op returning the prior result instead of being applied twice.
+// Each key keeps the data its write replaced: a retry runs its update function again against that
+// data and discards the output, so methods such as Swap and Increment return what the first call returned.
+func WithIdempotencyWindow(window time.Duration) ResourceOption {
+	return func(r *Resource) {
+		r.idempotency = newIdempotencyCache(window)
+	}
+}
+
+// newIdempotencyCache creates an empty cache remembering keys for window.
+func newIdempotencyCache(window time.Duration) *idempotencyCache {
+	return &idempotencyCache{window: window, keys: make(map[string]appliedWrite)}
+}
+
+// appliedWrite is a write remembered by its idempotency key.
+type appliedWrite struct {
+	before  string    // Stored data the write replaced
+	expires time.Time // When the key is forgotten
+}
+
+// idempotencyCache holds the keys of recently applied writes.
+type idempotencyCache struct {
+	window time.Duration
+
+	mu     sync.Mutex
+	keys   map[string]appliedWrite
+	expiry []expiringKey // Keys in the order they expire, which is the order they were remembered
+}
+
+// expiringKey is an entry of idempotencyCache's expiry queue.
+type expiringKey struct {
+	key     string
+	expires time.Time
+}
+
+// lookup returns the write applied within the window under key, if any.
+func (c *idempotencyCache) lookup(key string) (appliedWrite, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expire(time.Now())
+	w, ok := c.keys[key]
+	return w, ok
+}
+
+// remember records that the write under key replaced before.
+func (c *idempotencyCache) remember(key, before string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	c.expire(now)
+	expires := now.Add(c.window)
+	c.keys[key] = appliedWrite{before: before, expires: expires}
+	c.expiry = append(c.expiry, expiringKey{key: key, expires: expires})
+}
+
+// expire forgets the keys that expired by now. Keys expire in the order they were remembered,
+// so only the front of the queue needs checking. The caller must hold c.mu.
+func (c *idempotencyCache) expire(now time.Time) {
+	n := 0
+	for ; n < len(c.expiry) && !now.Before(c.expiry[n].expires); n++ {
+		e := c.expiry[n]
+		if w, ok := c.keys[e.key]; ok && w.expires.Equal(e.expires) {
+			delete(c.keys, e.key) // Not remembered again since this entry was queued
+		}
+	}
+	c.expiry = c.expiry[n:]
+}
+
+// idempotencyKeyFrom returns the idempotency key carried by ctx if the resource deduplicates writes.
+func (r *Resource) idempotencyKeyFrom(ctx context.Context) (string, bool) {
+	if r.idempotency == nil {
+		return "", false
+	}
+	key, ok := ctx.Value(idempotencyKey{}).(string)
+	return key, ok
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKeyAppliesOnce(t *testing.T) {
+	r := NewResource("", WithIdempotencyWindow(time.Hour))
+	ctx := WithIdempotencyKey(context.Background(), "request-1")
+
+	for i := 0; i < 2; i++ {
+		if err := r.AppendLine(ctx, "charge"); err != nil {
+			t.Fatalf("attempt %d: %v", i, err)
+		}
+	}
+	if data, _ := r.Read(ctx); data != "charge\n" || r.Version() != 1 {
+		t.Fatalf("got %q at version %d, want the write applied once", data, r.Version())
+	}
+
+	if err := r.AppendLine(WithIdempotencyKey(context.Background(), "request-2"), "refund"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := r.Read(ctx); data != "charge\nrefund\n" {
+		t.Fatalf("a different key was deduplicated: got %q", data)
+	}
+}
+
+func TestIdempotencyKeyExpires(t *testing.T) {
+	const window = 10 * time.Millisecond
+	r := NewResource("0", WithIdempotencyWindow(window))
+	ctx := WithIdempotencyKey(context.Background(), "retry")
+	r.Increment(ctx, 1)
+	time.Sleep(2 * window)
+	r.Increment(ctx, 1)
+	if data, _ := r.Read(ctx); data != "2" {
+		t.Fatalf("got %s, want the key forgotten after the window", data)
+	}
+}
+
+func TestIdempotencyKeyNotRememberedOnFailure(t *testing.T) {
+	r := NewResource("", WithIdempotencyWindow(time.Hour))
+	ctx := WithIdempotencyKey(context.Background(), "retry")
+	r.SetReadOnly(true)
+	if err := r.Write(ctx, "x"); !errors.Is(err, ErrReadOnly) {
+		t.Fatal(err)
+	}
+	r.SetReadOnly(false)
+	if err := r.Write(ctx, "x"); err != nil || r.Version() != 1 {
+		t.Fatalf("retry after a failed write: version %d (%v), want it applied", r.Version(), err)
+	}
+}
+
+func TestIdempotencyKeyIgnoredWithoutWindow(t *testing.T) {
+	r := NewResource("")
+	ctx := WithIdempotencyKey(context.Background(), "request")
+	r.Write(ctx, "a")
+	r.Write(ctx, "b")
+	if r.Version() != 2 {
+		t.Fatalf("version %d, want both writes applied without a window", r.Version())
+	}
+}
+
+func TestIdempotencyKeyReturnsPriorResult(t *testing.T) {
+	r := NewResource("10", WithIdempotencyWindow(time.Hour))
+	ctx := WithIdempotencyKey(context.Background(), "add")
+
+	for i := 0; i < 2; i++ {
+		if n, err := r.Increment(ctx, 5); err != nil || n != 15 {
+			t.Fatalf("attempt %d: got %d (%v), want 15", i, n, err)
+		}
+	}
+
+	swap := WithIdempotencyKey(context.Background(), "swap")
+	for i := 0; i < 2; i++ {
+		if old, err := r.Swap(swap, "next"); err != nil || old != "15" {
+			t.Fatalf("attempt %d: swapped out %q (%v), want 15", i, old, err)
+		}
+	}
+	if data, _ := r.Read(ctx); data != "next" || r.Version() != 2 {
+		t.Fatalf("got %q at version %d, want each keyed write applied once", data, r.Version())
+	}
+}
+
+func TestIdempotencyKeyEventHidesStoredForm(t *testing.T) {
+	r := NewResource("", WithIdempotencyWindow(time.Hour), WithTransformers(redactSecrets), WithEventTimeline())
+	ctx := WithIdempotencyKey(context.Background(), "retry")
+	r.Write(ctx, "secret")
+	r.Write(ctx, "secret")
+
+	events := r.Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if v := events[1].Value; v != "REDACTED" {
+		t.Fatalf("deduplicated write recorded %q, want the data as Read returns it", v)
+	}
+}
+
+func TestIdempotencyKeysExpireInOrder(t *testing.T) {
+	const window = 10 * time.Millisecond
+	c := newIdempotencyCache(window)
+	c.remember("a", "")
+	c.remember("b", "")
+	time.Sleep(2 * window)
+	c.remember("a", "") // Remembered again, so its first expiry must not forget it
+
+	if _, ok := c.lookup("a"); !ok {
+		t.Fatal("forgot a key remembered again within the window")
+	}
+	if _, ok := c.lookup("b"); ok {
+		t.Fatal("kept an expired key")
+	}
+	if len(c.keys) != 1 || len(c.expiry) != 1 {
+		t.Fatalf("holding %d keys and %d queued expiries, want 1 of each", len(c.keys), len(c.expiry))
+	}
+}
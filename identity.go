@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// WorkerIdentity identifies the client a worker acts on behalf of.
+type WorkerIdentity struct {
+	ID       int               // Numeric ID used for ordering and compact reports
+	Name     string            // Client name used in logs; defaults to "Worker <ID>"
+	Metadata map[string]string // Arbitrary client attributes such as a tenant
+}
+
+// String returns the identity's name, falling back to its numeric ID.
+func (id WorkerIdentity) String() string {
+	if id.Name != "" {
+		return id.Name
+	}
+	return fmt.Sprintf("Worker %d", id.ID)
+}
+
+// identityKey is the context key under which a WorkerIdentity is stored.
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying the given identity.
+func WithIdentity(ctx context.Context, id WorkerIdentity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFromContext returns the identity carried by ctx, if any.
+func IdentityFromContext(ctx context.Context) (WorkerIdentity, bool) {
+	id, ok := ctx.Value(identityKey{}).(WorkerIdentity)
+	return id, ok
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestIdentityFlowsIntoAuditAndLogs(t *testing.T) {
+	r := NewResource("initial", WithEventTimeline())
+	id := WorkerIdentity{ID: 7, Name: "billing-service", Metadata: map[string]string{"tenant": "acme"}}
+	w := NewWorker(id, r)
+
+	output := captureOutput(t, func() {
+		if err := w.WriteToResource(context.Background(), "invoice"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if !strings.Contains(output, "billing-service writing to resource") {
+		t.Fatalf("log does not name the client: %q", output)
+	}
+	if got := r.LastWriter(); got.ID != 7 || got.Name != "billing-service" || got.Metadata["tenant"] != "acme" {
+		t.Fatalf("last writer %+v, want %+v", got, id)
+	}
+	events := r.Events()
+	if len(events) != 1 || events[0].Worker.Name != "billing-service" {
+		t.Fatalf("events %+v do not record the client", events)
+	}
+}
+
+func TestIdentityString(t *testing.T) {
+	if got := (WorkerIdentity{ID: 3}).String(); got != "Worker 3" {
+		t.Fatalf("got %q", got)
+	}
+	if _, ok := IdentityFromContext(context.Background()); ok {
+		t.Fatal("identity found in a bare context")
+	}
+}
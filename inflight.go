@@ -0,0 +1,52 @@
+package main
+
+import "time"
+
+// inFlightOp is an operation that has started but not yet finished.
+type inFlightOp struct {
+	start  time.Time
+	worker int
+	op     string
+}
+
+// track registers ev as in flight until untrack is called for it.
+func (r *Resource) track(ev *Event) {
+	f := &inFlightOp{start: ev.Start, worker: ev.Worker.ID, op: ev.Op}
+	r.flightMu.Lock()
+	defer r.flightMu.Unlock()
+	if r.inFlight == nil {
+		r.inFlight = make(map[*inFlightOp]struct{})
+	}
+	r.inFlight[f] = struct{}{}
+	ev.flight = f
+}
+
+// untrack removes ev from the in-flight operations. It is safe to call more than once.
+func (r *Resource) untrack(ev Event) {
+	r.flightMu.Lock()
+	defer r.flightMu.Unlock()
+	delete(r.inFlight, ev.flight)
+	if r.landed != nil {
+		close(r.landed)
+		r.landed = nil
+	}
+}
+
+// OldestInFlight reports the longest-running operation that has not finished yet: how long it
+// has been running, the ID of the worker that issued it, and its kind, such as OpRead or OpWrite.
+// It includes operations still waiting for the lock, which helps spot a hung worker.
+// All results are zero if no operation is in flight.
+func (r *Resource) OldestInFlight() (age time.Duration, workerID int, op string) {
+	r.flightMu.Lock()
+	defer r.flightMu.Unlock()
+	var oldest *inFlightOp
+	for f := range r.inFlight {
+		if oldest == nil || f.start.Before(oldest.start) {
+			oldest = f
+		}
+	}
+	if oldest == nil {
+		return 0, 0, ""
+	}
+	return time.Since(oldest.start), oldest.worker, oldest.op
+}
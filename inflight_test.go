@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestOldestInFlight(t *testing.T) {
+	r := NewResource("initial")
+	if age, id, op := r.OldestInFlight(); age != 0 || id != 0 || op != "" {
+		t.Fatalf("idle resource reported %v %d %q", age, id, op)
+	}
+
+	// Worker 1 hangs holding the read lock; worker 2 then queues a write behind it
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.WithReadLock(WithIdentity(context.Background(), WorkerIdentity{ID: 1}), func(string) error {
+			<-release
+			return nil
+		})
+	}()
+	for {
+		if _, id, _ := r.OldestInFlight(); id == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	go r.Write(WithIdentity(context.Background(), WorkerIdentity{ID: 2}), "queued")
+	for r.WaitQueueDepth() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	age, id, op := r.OldestInFlight()
+	if id != 1 || op != OpRead || age < 10*time.Millisecond {
+		t.Fatalf("got worker %d %s running for %v, want worker 1's hung read", id, op, age)
+	}
+
+	close(release)
+	<-done
+	r.Flush(context.Background())
+	if age, _, _ := r.OldestInFlight(); age != 0 {
+		t.Fatalf("operations still reported in flight after finishing (%v)", age)
+	}
+}
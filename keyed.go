@@ -0,0 +1,478 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"time"
+)
+
+// defaultShards is the number of shards used when NewKeyedResource is given a non-positive count.
+const defaultShards = 16
+
+// KeyedResource is a key/value store whose keys are striped across independently locked shards,
+// so operations on keys in different shards do not contend with each other.
+type KeyedResource struct {
+	shards []*keyShard
+	hash   func(key string) uint32 // Maps keys to shards
+	order  *keyOrder               // Eviction bookkeeping; nil if the store is unbounded
+
+	coalescer *writeCoalescer // Buffered writes; nil if writes are applied immediately
+	queues    *keyQueues      // Per-key ordering of writes; nil if unordered
+
+	electionTTL time.Duration // Leadership lease used by Elect
+
+	initial map[string]string // Entries to preload, applied once every option is set
+}
+
+// keyShard holds the entries of one stripe of a KeyedResource.
+type keyShard struct {
+	mu      rwLock // Context-aware lock for the shard's entries
+	data    map[string]string
+	expires map[string]time.Time // Expiry of entries written with a TTL
+	metrics shardMetrics         // Operations served by the shard
+}
+
+// get returns the unexpired value stored under key. The shard must be locked.
+func (s *keyShard) get(key string) (string, bool) {
+	value, ok := s.data[key]
+	if !ok {
+		return "", false
+	}
+	if expires, ok := s.expires[key]; ok && !time.Now().Before(expires) {
+		return "", false // Expired entries are removed lazily by the next write
+	}
+	return value, true
+}
+
+// set stores value under key without an expiry. The shard must be write-locked.
+func (s *keyShard) set(key, value string) {
+	s.data[key] = value
+	delete(s.expires, key)
+}
+
+// setTTL makes key expire after ttl. The shard must be write-locked.
+func (s *keyShard) setTTL(key string, ttl time.Duration) {
+	if s.expires == nil {
+		s.expires = make(map[string]time.Time)
+	}
+	s.expires[key] = time.Now().Add(ttl)
+}
+
+// remove deletes key. The shard must be write-locked.
+func (s *keyShard) remove(key string) {
+	delete(s.data, key)
+	delete(s.expires, key)
+}
+
+// KeyedOption configures optional behavior of a KeyedResource.
+type KeyedOption func(*KeyedResource)
+
+// WithHash makes the store map keys to shards with hash instead of FNV-1a.
+// Tests can use it to force keys onto the same or different shards deterministically.
+func WithHash(hash func(key string) uint32) KeyedOption {
+	return func(k *KeyedResource) {
+		k.hash = hash
+	}
+}
+
+// WithInitialData preloads the store with a copy of data before any operation runs.
+// The caller's map is not retained, so later changes to it do not affect the store.
+func WithInitialData(data map[string]string) KeyedOption {
+	return func(k *KeyedResource) {
+		k.initial = make(map[string]string, len(data))
+		for key, value := range data {
+			k.initial[key] = value
+		}
+	}
+}
+
+// NewKeyedResource creates a new KeyedResource with the given number of shards.
+func NewKeyedResource(numShards int, opts ...KeyedOption) *KeyedResource {
+	if numShards <= 0 {
+		numShards = defaultShards
+	}
+	k := &KeyedResource{shards: make([]*keyShard, numShards), hash: fnvHash}
+	for i := range k.shards {
+		k.shards[i] = &keyShard{data: make(map[string]string)}
+	}
+	for _, opt := range opts {
+		opt(k)
+	}
+
+	// Preload after all options so the final hash and key cap apply
+	for key, value := range k.initial {
+		k.shard(key).set(key, value)
+		if k.order != nil {
+			for _, victim := range k.order.insert(key) {
+				k.shard(victim).remove(victim)
+			}
+		}
+	}
+	k.initial = nil
+	return k
+}
+
+// fnvHash is the default shard hash.
+func fnvHash(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
+
+// shardIndex returns the index of the shard holding key.
+func (k *KeyedResource) shardIndex(key string) int {
+	return int(k.hash(key) % uint32(len(k.shards)))
+}
+
+// shard returns the shard holding key.
+func (k *KeyedResource) shard(key string) *keyShard {
+	return k.shards[k.shardIndex(key)]
+}
+
+// Read returns the value stored under key and whether it was present.
+func (k *KeyedResource) Read(ctx context.Context, key string) (string, bool, error) {
+	select {
+	case <-ctx.Done():
+		return "", false, ctx.Err() // Return error if context is canceled
+	default:
+		if k.coalescer != nil {
+			k.flushKey(key) // Make any buffered write visible
+		}
+		s := k.shard(key)
+		defer s.observe(OpRead, time.Now())
+		if err := s.mu.RLock(ctx); err != nil { // Acquire the shard's read lock
+			return "", false, err
+		}
+		defer s.mu.RUnlock()
+		value, ok := s.get(key)
+		if ok && k.order != nil {
+			k.order.touch(key)
+		}
+		return value, ok, nil
+	}
+}
+
+// Write stores value under key, evicting other entries if the store is over its key cap.
+// With write coalescing enabled, the value is buffered and applied once the window elapses.
+func (k *KeyedResource) Write(ctx context.Context, key, value string) error {
+	release, err := k.enqueue(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if k.coalescer == nil {
+		return k.write(ctx, key, value)
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err() // Return error if context is canceled
+	default:
+		k.buffer(key, value)
+		return nil
+	}
+}
+
+// write applies value to key under the shard's write lock.
+func (k *KeyedResource) write(ctx context.Context, key, value string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err() // Return error if context is canceled
+	default:
+		s := k.shard(key)
+		defer s.observe(OpWrite, time.Now())
+		if err := s.mu.Lock(ctx); err != nil { // Acquire the shard's write lock
+			return err
+		}
+		s.set(key, value)
+		var victims []string
+		if k.order != nil {
+			victims = k.order.insert(key)
+		}
+		s.mu.Unlock()
+
+		k.evict(victims) // Evict outside the shard lock to avoid locking two shards at once
+		return nil
+	}
+}
+
+// WriteIfAbsent stores value under key only if the key is missing or expired, and reports whether it did.
+// A positive ttl makes the entry expire after ttl unless it is written again.
+func (k *KeyedResource) WriteIfAbsent(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	release, err := k.enqueue(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err() // Return error if context is canceled
+	default:
+		if k.coalescer != nil {
+			k.flushKey(key) // Check against the latest written value
+		}
+		s := k.shard(key)
+		defer s.observe(OpWrite, time.Now())
+		if err := s.mu.Lock(ctx); err != nil { // Acquire the shard's write lock
+			return false, err
+		}
+		if _, ok := s.get(key); ok {
+			s.mu.Unlock()
+			return false, nil
+		}
+		s.set(key, value)
+		if ttl > 0 {
+			s.setTTL(key, ttl)
+		}
+		var victims []string
+		if k.order != nil {
+			victims = k.order.insert(key)
+		}
+		s.mu.Unlock()
+
+		k.evict(victims) // Evict outside the shard lock to avoid locking two shards at once
+		return true, nil
+	}
+}
+
+// Merge stores every entry of updates atomically: no reader observes some of the updates without the others.
+// The shards involved are write-locked together in index order, so concurrent merges cannot deadlock.
+func (k *KeyedResource) Merge(ctx context.Context, updates map[string]string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err() // Return error if context is canceled
+	default:
+		start := time.Now()
+		involved := make([]bool, len(k.shards))
+		for key := range updates {
+			involved[k.shardIndex(key)] = true
+			if k.coalescer != nil {
+				k.discardPending(key) // The merged value supersedes any buffered write
+			}
+		}
+		for i, s := range k.shards {
+			if !involved[i] {
+				continue
+			}
+			if err := s.mu.Lock(ctx); err != nil {
+				for j := 0; j < i; j++ {
+					if involved[j] {
+						k.shards[j].mu.Unlock()
+					}
+				}
+				return err
+			}
+		}
+
+		var victims []string
+		for key, value := range updates {
+			k.shard(key).set(key, value)
+			if k.order != nil {
+				victims = append(victims, k.order.insert(key)...)
+			}
+		}
+		for i, s := range k.shards {
+			if involved[i] {
+				s.mu.Unlock()
+				s.observe(OpWrite, start)
+			}
+		}
+
+		k.evict(victims) // Evict outside the shard locks
+		return nil
+	}
+}
+
+// Delete removes key from the store. Deleting a missing key is a no-op.
+func (k *KeyedResource) Delete(ctx context.Context, key string) error {
+	release, err := k.enqueue(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer release()
+	select {
+	case <-ctx.Done():
+		return ctx.Err() // Return error if context is canceled
+	default:
+		if k.coalescer != nil {
+			k.discardPending(key) // A buffered write must not resurrect the key
+		}
+		s := k.shard(key)
+		defer s.observe(OpWrite, time.Now())
+		if err := s.mu.Lock(ctx); err != nil { // Acquire the shard's write lock
+			return err
+		}
+		defer s.mu.Unlock()
+		s.remove(key)
+		if k.order != nil {
+			k.order.remove(key)
+		}
+		return nil
+	}
+}
+
+// DeleteIf removes key only if its current value equals expected, and reports whether it did.
+// The comparison and deletion happen atomically under the shard's write lock.
+func (k *KeyedResource) DeleteIf(ctx context.Context, key, expected string) (bool, error) {
+	release, err := k.enqueue(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err() // Return error if context is canceled
+	default:
+		if k.coalescer != nil {
+			k.flushKey(key) // Compare against the latest written value
+		}
+		s := k.shard(key)
+		defer s.observe(OpWrite, time.Now())
+		if err := s.mu.Lock(ctx); err != nil { // Acquire the shard's write lock
+			return false, err
+		}
+		defer s.mu.Unlock()
+		if value, ok := s.get(key); !ok || value != expected {
+			return false, nil
+		}
+		s.remove(key)
+		if k.order != nil {
+			k.order.remove(key)
+		}
+		return true, nil
+	}
+}
+
+// Redeem atomically returns the value under key and deletes it, so that under concurrent calls
+// exactly one caller gets a given one-time token. ok is false if the key was missing or expired.
+func (k *KeyedResource) Redeem(ctx context.Context, key string) (value string, ok bool, err error) {
+	release, err := k.enqueue(ctx, key)
+	if err != nil {
+		return "", false, err
+	}
+	defer release()
+	select {
+	case <-ctx.Done():
+		return "", false, ctx.Err() // Return error if context is canceled
+	default:
+		if k.coalescer != nil {
+			k.flushKey(key) // Redeem the latest written value
+		}
+		s := k.shard(key)
+		defer s.observe(OpWrite, time.Now())
+		if err := s.mu.Lock(ctx); err != nil { // Acquire the shard's write lock
+			return "", false, err
+		}
+		defer s.mu.Unlock()
+		value, ok = s.get(key)
+		if !ok {
+			return "", false, nil
+		}
+		s.remove(key)
+		if k.order != nil {
+			k.order.remove(key)
+		}
+		return value, true, nil
+	}
+}
+
+// Errors returned by Touch.
+var (
+	ErrTokenInvalid = errors.New("token is not known")
+	ErrTokenExpired = errors.New("token has expired")
+)
+
+// Touch extends the expiry of the entry under token by extend, keeping an active session alive.
+// It returns ErrTokenExpired if the entry has expired and ErrTokenInvalid if it was never stored
+// or has been deleted. The check and the extension happen in one critical section under the
+// shard's write lock. Entries stored without a TTL never expire and are left unchanged.
+func (k *KeyedResource) Touch(ctx context.Context, token string, extend time.Duration) error {
+	release, err := k.enqueue(ctx, token)
+	if err != nil {
+		return err
+	}
+	defer release()
+	select {
+	case <-ctx.Done():
+		return ctx.Err() // Return error if context is canceled
+	default:
+		if k.coalescer != nil {
+			k.flushKey(token) // Check against the latest written value
+		}
+		s := k.shard(token)
+		defer s.observe(OpWrite, time.Now())
+		if err := s.mu.Lock(ctx); err != nil { // Acquire the shard's write lock
+			return err
+		}
+		defer s.mu.Unlock()
+		if _, ok := s.data[token]; !ok {
+			return ErrTokenInvalid
+		}
+		expires, ok := s.expires[token]
+		if !ok {
+			return nil
+		}
+		if !time.Now().Before(expires) {
+			return ErrTokenExpired
+		}
+		s.expires[token] = expires.Add(extend)
+		if k.order != nil {
+			k.order.touch(token)
+		}
+		return nil
+	}
+}
+
+// Range calls fn for every entry, stopping early if fn returns false.
+// All shards are read-locked for the duration, so fn sees a consistent snapshot with no torn view
+// across shards, while writers wait. fn must not call back into the store, or it will deadlock.
+func (k *KeyedResource) Range(ctx context.Context, fn func(key, value string) bool) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err() // Return error if context is canceled
+	default:
+		if err := k.Flush(ctx); err != nil {
+			return err
+		}
+		if err := k.rlockAll(ctx); err != nil {
+			return err
+		}
+		defer k.runlockAll()
+		for _, s := range k.shards {
+			for key := range s.data {
+				value, ok := s.get(key)
+				if !ok {
+					continue // Skip expired entries
+				}
+				var more bool
+				if err := callSafely(func() error { more = fn(key, value); return nil }); err != nil {
+					return err
+				}
+				if !more {
+					return nil
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// rlockAll read-locks every shard in index order, releasing any already held if ctx is canceled.
+func (k *KeyedResource) rlockAll(ctx context.Context) error {
+	for i, s := range k.shards {
+		if err := s.mu.RLock(ctx); err != nil {
+			for _, held := range k.shards[:i] {
+				held.mu.RUnlock()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// runlockAll releases the read locks taken by rlockAll.
+func (k *KeyedResource) runlockAll() {
+	for _, s := range k.shards {
+		s.mu.RUnlock()
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// byFirstByte is a shard hash that places keys by their first byte, so tests control shard placement.
+func byFirstByte(key string) uint32 {
+	return uint32(key[0])
+}
+
+func TestWithHashSeparatesShards(t *testing.T) {
+	k := NewKeyedResource(2, WithHash(byFirstByte))
+	if a, b := k.shardIndex("a"), k.shardIndex("b"); a == b {
+		t.Fatalf("keys a and b share shard %d", a)
+	}
+	ctx := context.Background()
+
+	// Hold the shard of "a" and check that a write to "b" does not wait for it
+	held := k.shard("a")
+	if err := held.mu.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	writeCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := k.Write(writeCtx, "b", "free"); err != nil {
+		t.Fatalf("write to another shard contended: %v", err)
+	}
+
+	blockedCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := k.Write(blockedCtx, "a?", "blocked"); err != context.DeadlineExceeded {
+		t.Fatalf("write to the held shard got %v, want DeadlineExceeded", err)
+	}
+	held.mu.Unlock()
+
+	if value, ok, _ := k.Read(ctx, "b"); !ok || value != "free" {
+		t.Fatalf("got %q, %v", value, ok)
+	}
+}
+
+func TestWithInitialDataIsCopied(t *testing.T) {
+	source := map[string]string{"alpha": "1", "beta": "2"}
+	k := NewKeyedResource(4, WithInitialData(source))
+	source["alpha"] = "changed"
+	source["gamma"] = "added"
+	delete(source, "beta")
+
+	ctx := context.Background()
+	for key, want := range map[string]string{"alpha": "1", "beta": "2"} {
+		if value, ok, _ := k.Read(ctx, key); !ok || value != want {
+			t.Errorf("key %q: got %q, %v, want the preloaded %q", key, value, ok, want)
+		}
+	}
+	if _, ok, _ := k.Read(ctx, "gamma"); ok {
+		t.Error("key added to the source map after construction is visible")
+	}
+}
+
+func TestWithInitialDataRespectsKeyCap(t *testing.T) {
+	k := NewKeyedResource(4, WithInitialData(map[string]string{"a": "1", "b": "2", "c": "3"}), WithMaxKeys(2, EvictFIFO))
+	n := 0
+	k.Range(context.Background(), func(string, string) bool { n++; return true })
+	if n != 2 {
+		t.Fatalf("preloaded %d keys, want the cap of 2", n)
+	}
+}
+
+func TestRangeStopsEarly(t *testing.T) {
+	k := NewKeyedResource(4, WithInitialData(map[string]string{"a": "1", "b": "2", "c": "3", "d": "4"}))
+	ctx := context.Background()
+
+	seen := make(map[string]string)
+	if err := k.Range(ctx, func(key, value string) bool {
+		seen[key] = value
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 4 || seen["c"] != "3" {
+		t.Fatalf("full range saw %v", seen)
+	}
+
+	calls := 0
+	if err := k.Range(ctx, func(string, string) bool {
+		calls++
+		return calls < 2
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("fn called %d times after returning false on the second, want 2", calls)
+	}
+}
+
+func TestRangeSkipsExpired(t *testing.T) {
+	k := NewKeyedResource(2)
+	ctx := context.Background()
+	k.Write(ctx, "kept", "1")
+	k.WriteIfAbsent(ctx, "expired", "2", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	k.Range(ctx, func(key, _ string) bool {
+		if key == "expired" {
+			t.Error("range visited an expired entry")
+		}
+		return true
+	})
+}
+
+func TestDeleteIfAfterConcurrentUpdate(t *testing.T) {
+	k := NewKeyedResource(2)
+	ctx := context.Background()
+	k.Write(ctx, "session", "v1")
+
+	// A worker reads v1 and decides to delete it, but another worker updates the key first
+	seen, _, _ := k.Read(ctx, "session")
+	updated := make(chan error)
+	go func() { updated <- k.Write(ctx, "session", "v2") }()
+	if err := <-updated; err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := k.DeleteIf(ctx, "session", seen)
+	if err != nil || deleted {
+		t.Fatalf("got deleted %v (%v), want the updated key kept", deleted, err)
+	}
+	if value, ok, _ := k.Read(ctx, "session"); !ok || value != "v2" {
+		t.Fatalf("got %q, %v, want the update kept", value, ok)
+	}
+}
+
+func TestDeleteIfConcurrentExactlyOnce(t *testing.T) {
+	k := NewKeyedResource(2)
+	ctx := context.Background()
+	k.Write(ctx, "token", "v1")
+
+	const callers = 16
+	results := make(chan bool, callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			deleted, err := k.DeleteIf(ctx, "token", "v1")
+			if err != nil {
+				t.Error(err)
+			}
+			results <- deleted
+		}()
+	}
+	deletions := 0
+	for i := 0; i < callers; i++ {
+		if <-results {
+			deletions++
+		}
+	}
+	if deletions != 1 {
+		t.Fatalf("%d callers deleted the key, want exactly 1", deletions)
+	}
+}
+
+func TestMergeConcurrent(t *testing.T) {
+	k := NewKeyedResource(4)
+	ctx := context.Background()
+	const mergers = 8
+
+	var wg sync.WaitGroup
+	for i := 0; i < mergers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := k.Merge(ctx, map[string]string{
+				fmt.Sprint("own-", i): fmt.Sprint(i), // Disjoint across mergers
+				"shared-a":            fmt.Sprint(i), // Overlapping: written by every merger
+				"shared-b":            fmt.Sprint(i),
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+
+	// Each merge is atomic, so a reader never sees the shared keys from different merges
+	for i := 0; i < 20; i++ {
+		var a, b string
+		k.Range(ctx, func(key, value string) bool {
+			switch key {
+			case "shared-a":
+				a = value
+			case "shared-b":
+				b = value
+			}
+			return true
+		})
+		if a != b {
+			t.Fatalf("torn merge: shared-a %q, shared-b %q", a, b)
+		}
+	}
+	wg.Wait()
+
+	for i := 0; i < mergers; i++ {
+		if value, ok, _ := k.Read(ctx, fmt.Sprint("own-", i)); !ok || value != fmt.Sprint(i) {
+			t.Errorf("own-%d: got %q, %v", i, value, ok)
+		}
+	}
+	a, _, _ := k.Read(ctx, "shared-a")
+	b, _, _ := k.Read(ctx, "shared-b")
+	if a != b || a == "" {
+		t.Fatalf("shared keys ended as %q and %q, want both from the last merge", a, b)
+	}
+}
+
+func TestRedeemConcurrentExactlyOnce(t *testing.T) {
+	k := NewKeyedResource(4)
+	ctx := context.Background()
+	k.Write(ctx, "token", "grant")
+
+	const callers = 16
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		redeemed []string
+	)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, ok, err := k.Redeem(ctx, "token")
+			if err != nil {
+				t.Error(err)
+			}
+			if ok {
+				mu.Lock()
+				redeemed = append(redeemed, value)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(redeemed) != 1 || redeemed[0] != "grant" {
+		t.Fatalf("redeemed %v, want the token exactly once", redeemed)
+	}
+	if _, ok, _ := k.Read(ctx, "token"); ok {
+		t.Fatal("token still present after being redeemed")
+	}
+}
+
+func TestRedeemExpired(t *testing.T) {
+	k := NewKeyedResource(4)
+	ctx := context.Background()
+	k.WriteIfAbsent(ctx, "token", "grant", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok, err := k.Redeem(ctx, "token"); ok || err != nil {
+		t.Fatalf("redeemed an expired token (%v)", err)
+	}
+}
+
+func TestTouchValidToken(t *testing.T) {
+	const ttl = 30 * time.Millisecond
+	k := NewKeyedResource(4)
+	ctx := context.Background()
+	k.WriteIfAbsent(ctx, "session", "alice", ttl)
+
+	if err := k.Touch(ctx, "session", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * ttl) // Past the original expiry
+	if value, ok, _ := k.Read(ctx, "session"); !ok || value != "alice" {
+		t.Fatalf("touched token expired: got %q, %v", value, ok)
+	}
+}
+
+func TestTouchExpiredToken(t *testing.T) {
+	k := NewKeyedResource(4)
+	ctx := context.Background()
+	k.WriteIfAbsent(ctx, "session", "alice", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if err := k.Touch(ctx, "session", time.Hour); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("got %v, want ErrTokenExpired", err)
+	}
+	if _, ok, _ := k.Read(ctx, "session"); ok {
+		t.Fatal("touching an expired token revived it")
+	}
+}
+
+func TestTouchUnknownToken(t *testing.T) {
+	k := NewKeyedResource(4)
+	ctx := context.Background()
+	if err := k.Touch(ctx, "missing", time.Hour); !errors.Is(err, ErrTokenInvalid) {
+		t.Fatalf("got %v, want ErrTokenInvalid", err)
+	}
+	k.WriteIfAbsent(ctx, "deleted", "x", time.Hour)
+	k.Delete(ctx, "deleted")
+	if err := k.Touch(ctx, "deleted", time.Hour); !errors.Is(err, ErrTokenInvalid) {
+		t.Fatalf("deleted token: got %v, want ErrTokenInvalid", err)
+	}
+}
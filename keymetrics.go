@@ -0,0 +1,45 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// shardMetrics counts the operations served by one shard of a KeyedResource.
+type shardMetrics struct {
+	reads, writes uint64
+	latency       histogram // Time taken by each operation, including the lock wait
+}
+
+// observe records an operation of kind op on the shard that started at start.
+func (s *keyShard) observe(op string, start time.Time) {
+	if op == OpRead {
+		atomic.AddUint64(&s.metrics.reads, 1)
+	} else {
+		atomic.AddUint64(&s.metrics.writes, 1)
+	}
+	s.metrics.latency.observe(time.Since(start))
+}
+
+// ShardMetrics is a snapshot of the operations served by one shard.
+type ShardMetrics struct {
+	Shard   int       // Index of the shard
+	Reads   uint64    // Reads of keys in the shard
+	Writes  uint64    // Writes and deletes of keys in the shard; a Merge counts once per shard it touches
+	Latency Histogram // Time taken by the shard's operations, including the lock wait
+}
+
+// ShardMetrics returns a snapshot of each shard's counters in shard index order.
+// A shard with far more operations than the rest points at a hot key or a poor hash.
+func (k *KeyedResource) ShardMetrics() []ShardMetrics {
+	out := make([]ShardMetrics, len(k.shards))
+	for i, s := range k.shards {
+		out[i] = ShardMetrics{
+			Shard:   i,
+			Reads:   atomic.LoadUint64(&s.metrics.reads),
+			Writes:  atomic.LoadUint64(&s.metrics.writes),
+			Latency: s.metrics.latency.snapshot(),
+		}
+	}
+	return out
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestShardMetricsRevealHotShard(t *testing.T) {
+	// Keys starting with "h" land on shard 0 (byte 104), so every hot write hits one shard
+	k := NewKeyedResource(4, WithHash(byFirstByte))
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		k.Write(ctx, fmt.Sprint("hot-", i), "v")
+	}
+	k.Write(ctx, "a", "v") // Shard 1
+	k.Read(ctx, "b")       // Shard 2
+
+	metrics := k.ShardMetrics()
+	if len(metrics) != 4 {
+		t.Fatalf("got metrics for %d shards, want 4", len(metrics))
+	}
+	for i, m := range metrics {
+		if m.Shard != i {
+			t.Errorf("metrics %d labeled shard %d", i, m.Shard)
+		}
+	}
+	hot := metrics[k.shardIndex("hot")]
+	if hot.Writes != 20 || hot.Latency.Count != 20 {
+		t.Fatalf("hot shard counted %d writes and %d latencies, want 20", hot.Writes, hot.Latency.Count)
+	}
+	for _, m := range metrics {
+		if m.Shard != hot.Shard && m.Writes+m.Reads > 1 {
+			t.Errorf("shard %d served %d operations, want the hot shard to dominate", m.Shard, m.Writes+m.Reads)
+		}
+	}
+	if m := metrics[k.shardIndex("b")]; m.Reads != 1 {
+		t.Errorf("shard of b counted %d reads, want 1", m.Reads)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrLeased is returned by Write while a read lease is held on the resource.
+var ErrLeased = errors.New("resource is leased for reading")
+
+// Lease is a read lease that rejects writes to a resource until it expires or is released.
+type Lease struct {
+	resource *Resource
+	id       uint64
+	Expires  time.Time // Time at which the lease lapses on its own
+}
+
+// AcquireReadLease takes a read lease for the given duration, during which every write fails with ErrLeased.
+// Leases always expire after duration, so a holder that never releases cannot block writers forever.
+func (r *Resource) AcquireReadLease(ctx context.Context, duration time.Duration) (Lease, error) {
+	if err := r.checkOpen(); err != nil {
+		return Lease{}, err
+	}
+	select {
+	case <-ctx.Done():
+		return Lease{}, ctx.Err() // Return error if context is canceled
+	default:
+		if err := r.mu.Lock(ctx); err != nil { // Wait for in-flight writes so the leased value is stable
+			return Lease{}, err
+		}
+		defer r.mu.Unlock()
+
+		r.leaseMu.Lock()
+		defer r.leaseMu.Unlock()
+		if r.leases == nil {
+			r.leases = make(map[uint64]time.Time)
+		}
+		r.nextLease++
+		lease := Lease{resource: r, id: r.nextLease, Expires: time.Now().Add(duration)}
+		r.leases[lease.id] = lease.Expires
+		return lease, nil
+	}
+}
+
+// Release ends the lease early. Releasing an expired or already released lease is a no-op.
+func (l Lease) Release() {
+	if l.resource == nil {
+		return
+	}
+	l.resource.leaseMu.Lock()
+	defer l.resource.leaseMu.Unlock()
+	delete(l.resource.leases, l.id)
+}
+
+// leased reports whether any unexpired lease is held, discarding expired ones.
+func (r *Resource) leased() bool {
+	r.leaseMu.Lock()
+	defer r.leaseMu.Unlock()
+	now := time.Now()
+	for id, expires := range r.leases {
+		if !now.Before(expires) {
+			delete(r.leases, id) // Lease lapsed on its own
+			continue
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReadLeaseBlocksWritesUntilRelease(t *testing.T) {
+	r := NewResource("initial")
+	ctx := context.Background()
+
+	lease, err := r.AcquireReadLease(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error, 1)
+	go func() { errs <- r.Write(ctx, "concurrent") }()
+	if err := <-errs; !errors.Is(err, ErrLeased) {
+		t.Fatalf("write under a lease: got %v, want ErrLeased", err)
+	}
+	if data, err := r.Read(ctx); err != nil || data != "initial" {
+		t.Fatalf("read under a lease: got %q (%v)", data, err)
+	}
+
+	lease.Release()
+	if err := r.Write(ctx, "after"); err != nil {
+		t.Fatalf("write after release: %v", err)
+	}
+	lease.Release() // Releasing twice is a no-op
+}
+
+func TestReadLeaseExpires(t *testing.T) {
+	r := NewResource("initial")
+	if _, err := r.AcquireReadLease(context.Background(), 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if err := r.Write(context.Background(), "after"); err != nil {
+		t.Fatalf("write after the lease expired: %v", err)
+	}
+}
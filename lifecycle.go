@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrClosed is returned by operations on a resource that has been closed.
+var ErrClosed = errors.New("resource is closed")
+
+// OnClose registers hook to run when the resource is closed, such as flushing or persisting state.
+// Hooks run in reverse registration order.
+func (r *Resource) OnClose(hook func(ctx context.Context) error) {
+	r.hooksMu.Lock()
+	defer r.hooksMu.Unlock()
+	r.closeHooks = append(r.closeHooks, hook)
+}
+
+// Close marks the resource unusable, runs the registered hooks in LIFO order, and closes every
+// subscription. All hook errors are returned joined together. Operations issued after Close
+// return ErrClosed, as does a second call to Close.
+func (r *Resource) Close(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&r.closed, 0, 1) {
+		return ErrClosed
+	}
+	close(r.closing) // Release a writer blocked on a subscriber, which holds subsMu and the write lock
+
+	r.hooksMu.Lock()
+	hooks := r.closeHooks
+	r.closeHooks = nil
+	r.hooksMu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	r.modeMu.Lock()
+	r.broadcastMode() // Release WaitWritable callers so they see ErrClosed
+	r.modeMu.Unlock()
+
+	r.subsMu.Lock()
+	for s := range r.subs {
+		s.stopOnce.Do(func() { close(s.done) })
+		r.removeSubscriber(s, ErrClosed)
+	}
+	r.subsMu.Unlock()
+	return errors.Join(errs...)
+}
+
+// checkOpen returns ErrClosed once the resource has been closed.
+func (r *Resource) checkOpen() error {
+	if atomic.LoadInt32(&r.closed) == 1 {
+		return ErrClosed
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCloseRunsHooksInReverseOrder(t *testing.T) {
+	r := NewResource("initial")
+	var order []int
+	for i := 1; i <= 3; i++ {
+		i := i
+		r.OnClose(func(ctx context.Context) error {
+			order = append(order, i)
+			return nil
+		})
+	}
+	hookErr := errors.New("flush failed")
+	r.OnClose(func(ctx context.Context) error { return hookErr })
+
+	if err := r.Close(context.Background()); !errors.Is(err, hookErr) {
+		t.Fatalf("Close returned %v, want the hook error", err)
+	}
+	if len(order) != 3 || order[0] != 3 || order[1] != 2 || order[2] != 1 {
+		t.Fatalf("hooks ran in order %v, want [3 2 1]", order)
+	}
+
+	if _, err := r.Read(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Read after Close: got %v, want ErrClosed", err)
+	}
+	if err := r.Write(context.Background(), "x"); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Write after Close: got %v, want ErrClosed", err)
+	}
+	if err := r.Close(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Fatalf("second Close: got %v, want ErrClosed", err)
+	}
+}
+
+func TestCloseReleasesWriterBlockedOnSubscriber(t *testing.T) {
+	r := NewResource("initial")
+	sub := r.Subscribe(SubscribeOptions{Buffer: 1, Overflow: OverflowBlock})
+	if err := r.Write(context.Background(), "first"); err != nil {
+		t.Fatal(err)
+	}
+
+	written := make(chan error, 1)
+	go func() { written <- r.Write(context.Background(), "second") }() // Blocks: the buffer is full
+	for !r.IsWriteLocked() {
+		time.Sleep(time.Millisecond)
+	}
+
+	closed := make(chan error, 1)
+	go func() { closed <- r.Close(context.Background()) }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close deadlocked on the blocked writer")
+	}
+	select {
+	case <-written:
+	case <-time.After(2 * time.Second):
+		t.Fatal("blocked writer was not released")
+	}
+	if err := sub.Err(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("subscription error %v, want ErrClosed", err)
+	}
+}
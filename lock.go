@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// rwLock is a writer-preferring read-write lock whose acquisition can be abandoned via a context.
+// Unlike sync.RWMutex it supports atomically downgrading a held write lock to a read lock.
+// The zero value is an unlocked lock. As with sync.RWMutex, read locks must not be taken recursively.
+type rwLock struct {
+	mu             sync.Mutex
+	readers        int           // Number of readers holding the lock
+	writer         bool          // Whether a writer holds the lock
+	waitingWriters int           // Writers waiting to acquire; new readers queue behind them
+	changed        chan struct{} // Closed and replaced whenever waiters may be able to proceed
+	queued         int64         // Goroutines blocked waiting to acquire the lock
+}
+
+// Lock acquires the write lock, or returns the context error if ctx is done first.
+func (l *rwLock) Lock(ctx context.Context) error {
+	l.mu.Lock()
+	l.waitingWriters++
+	if l.writer || l.readers > 0 {
+		atomic.AddInt64(&l.queued, 1)
+		defer atomic.AddInt64(&l.queued, -1)
+	}
+	for l.writer || l.readers > 0 {
+		if err := l.wait(ctx); err != nil {
+			l.waitingWriters--
+			l.broadcast() // Readers queued behind this writer may proceed
+			l.mu.Unlock()
+			return err
+		}
+	}
+	l.waitingWriters--
+	l.writer = true
+	l.mu.Unlock()
+	return nil
+}
+
+// TryLock acquires the write lock if it is free, without blocking.
+func (l *rwLock) TryLock() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.writer || l.readers > 0 {
+		return false
+	}
+	l.writer = true
+	return true
+}
+
+// Unlock releases the write lock.
+func (l *rwLock) Unlock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.writer {
+		panic("rwLock: Unlock of unlocked lock")
+	}
+	l.writer = false
+	l.broadcast()
+}
+
+// RLock acquires a read lock, or returns the context error if ctx is done first.
+func (l *rwLock) RLock(ctx context.Context) error {
+	l.mu.Lock()
+	if l.writer || l.waitingWriters > 0 {
+		atomic.AddInt64(&l.queued, 1)
+		defer atomic.AddInt64(&l.queued, -1)
+	}
+	for l.writer || l.waitingWriters > 0 {
+		if err := l.wait(ctx); err != nil {
+			l.mu.Unlock()
+			return err
+		}
+	}
+	l.readers++
+	l.mu.Unlock()
+	return nil
+}
+
+// TryRLock acquires a read lock if no writer holds or is waiting for the lock, without blocking.
+func (l *rwLock) TryRLock() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.writer || l.waitingWriters > 0 {
+		return false
+	}
+	l.readers++
+	return true
+}
+
+// RUnlock releases a read lock.
+func (l *rwLock) RUnlock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.readers == 0 {
+		panic("rwLock: RUnlock of unlocked lock")
+	}
+	l.readers--
+	if l.readers == 0 {
+		l.broadcast()
+	}
+}
+
+// Downgrade atomically converts a held write lock into a read lock.
+// Waiting readers may proceed immediately, while writers stay blocked until every read lock is released.
+func (l *rwLock) Downgrade() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.writer {
+		panic("rwLock: Downgrade of unlocked lock")
+	}
+	l.writer = false
+	l.readers++
+	l.broadcast()
+}
+
+// Queued returns the number of goroutines currently blocked waiting to acquire the lock.
+func (l *rwLock) Queued() int {
+	return int(atomic.LoadInt64(&l.queued))
+}
+
+// wait releases l.mu until the lock state changes or ctx is done, then reacquires it.
+// It must be called with l.mu held.
+func (l *rwLock) wait(ctx context.Context) error {
+	if l.changed == nil {
+		l.changed = make(chan struct{})
+	}
+	changed := l.changed
+	l.mu.Unlock()
+	defer l.mu.Lock()
+	select {
+	case <-changed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// broadcast wakes every waiter so it can recheck the lock state. It must be called with l.mu held.
+func (l *rwLock) broadcast() {
+	if l.changed != nil {
+		close(l.changed)
+		l.changed = nil
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// ResourceLocker exposes a resource's lock through the method set of sync.RWMutex,
+// for code that expects a sync.Locker. The context-aware methods of Resource remain the primary API.
+//
+// The Locker path ignores contexts and timeouts: Lock and RLock wait for as long as it takes.
+// The lock is not reentrant, so a caller holding it must not call the resource's own methods
+// that take the same lock, or it will deadlock.
+type ResourceLocker struct {
+	resource *Resource
+}
+
+// Locker returns a ResourceLocker for the resource's lock.
+func (r *Resource) Locker() *ResourceLocker {
+	return &ResourceLocker{resource: r}
+}
+
+// Lock acquires the write lock.
+func (l *ResourceLocker) Lock() {
+	_ = l.resource.mu.Lock(context.Background()) // Cannot fail without a deadline
+}
+
+// Unlock releases the write lock.
+func (l *ResourceLocker) Unlock() {
+	l.resource.mu.Unlock()
+}
+
+// RLock acquires a read lock.
+func (l *ResourceLocker) RLock() {
+	_ = l.resource.mu.RLock(context.Background()) // Cannot fail without a deadline
+}
+
+// RUnlock releases a read lock.
+func (l *ResourceLocker) RUnlock() {
+	l.resource.mu.RUnlock()
+}
+
+// RLocker returns a sync.Locker whose Lock and Unlock take and release a read lock.
+func (l *ResourceLocker) RLocker() sync.Locker {
+	return readLocker{l}
+}
+
+// readLocker adapts the read-lock methods of a ResourceLocker to sync.Locker.
+type readLocker struct {
+	l *ResourceLocker
+}
+
+func (r readLocker) Lock()   { r.l.RLock() }
+func (r readLocker) Unlock() { r.l.RUnlock() }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockerGuardsCriticalSection(t *testing.T) {
+	r := NewResource("initial")
+	var l sync.Locker = r.Locker()
+
+	// An unsynchronized read-modify-write counter is exact only if the Locker excludes writers
+	counter := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.Lock()
+				n := counter
+				time.Sleep(time.Microsecond)
+				counter = n + 1
+				l.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if counter != 800 {
+		t.Fatalf("counter %d, want 800", counter)
+	}
+}
+
+func TestLockerSharesResourceLock(t *testing.T) {
+	r := NewResource("initial")
+	l := r.Locker()
+
+	l.Lock()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := r.Read(ctx); err == nil {
+		t.Fatal("read succeeded while the Locker held the write lock")
+	}
+	l.Unlock()
+
+	rl := l.RLocker()
+	rl.Lock()
+	if data, err := r.Read(context.Background()); err != nil || data != "initial" {
+		t.Fatalf("read alongside the Locker's read lock: %q (%v)", data, err)
+	}
+	if readers, _ := r.mu.(lockInspector).Holders(); readers != 1 {
+		t.Fatalf("%d readers hold the resource lock, want the RLocker's one", readers)
+	}
+	rl.Unlock()
+}
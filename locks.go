@@ -0,0 +1,95 @@
+package main
+
+import "context"
+
+// ResourceLock is the lock guarding a Resource's data. Lock and RLock must give up and return
+// the context error if ctx is done before the lock is acquired.
+//
+// An implementation may also provide Downgrade() to atomically convert a held write lock into a
+// read lock, and Queued() int to report how many goroutines are waiting. Without Downgrade,
+// WriteAndDowngrade keeps the write lock while its callback runs; without Queued, WaitQueueDepth reports zero.
+// Holders() (readers, writers int) lets LockTimeoutError report the contention an operation waited behind.
+type ResourceLock interface {
+	Lock(ctx context.Context) error
+	Unlock()
+	RLock(ctx context.Context) error
+	RUnlock()
+	TryRLock() bool
+}
+
+// lockDowngrader is implemented by locks that support atomic write-to-read downgrades.
+type lockDowngrader interface {
+	Downgrade()
+}
+
+// lockQueue is implemented by locks that can report their number of waiters.
+type lockQueue interface {
+	Queued() int
+}
+
+// WithLock makes the resource guard its data with a lock created by newLock instead of the
+// default writer-preferring read-write lock. newLock is called again for each Clone, so every
+// resource has its own lock.
+func WithLock(newLock func() ResourceLock) ResourceOption {
+	return func(r *Resource) {
+		r.newLock = newLock
+	}
+}
+
+// NewRWLock returns the default lock: a writer-preferring read-write lock that supports downgrades.
+func NewRWLock() ResourceLock {
+	return &rwLock{}
+}
+
+// NewExclusiveLock returns a channel-based lock that admits a single holder at a time,
+// treating read locks like write locks. It is useful as a baseline when comparing lock strategies.
+func NewExclusiveLock() ResourceLock {
+	return make(exclusiveLock, 1)
+}
+
+// exclusiveLock is a mutual-exclusion lock built on a one-slot channel.
+type exclusiveLock chan struct{}
+
+// Lock acquires the lock, or returns the context error if ctx is done first.
+func (l exclusiveLock) Lock(ctx context.Context) error {
+	select {
+	case l <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Unlock releases the lock.
+func (l exclusiveLock) Unlock() {
+	select {
+	case <-l:
+	default:
+		panic("exclusiveLock: Unlock of unlocked lock")
+	}
+}
+
+// RLock acquires the lock exclusively, like Lock.
+func (l exclusiveLock) RLock(ctx context.Context) error {
+	return l.Lock(ctx)
+}
+
+// RUnlock releases the lock, like Unlock.
+func (l exclusiveLock) RUnlock() {
+	l.Unlock()
+}
+
+// TryRLock acquires the lock if it is free, without blocking.
+func (l exclusiveLock) TryRLock() bool {
+	select {
+	case l <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Queued returns zero: waiters on a channel cannot be counted.
+func (l exclusiveLock) Queued() int {
+	return 0
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockImplementations are the lock strategies every correctness test runs against.
+var lockImplementations = []struct {
+	name    string
+	newLock func() ResourceLock
+}{
+	{"rwLock", NewRWLock},
+	{"exclusiveLock", NewExclusiveLock},
+}
+
+func TestLocksMutualExclusion(t *testing.T) {
+	for _, impl := range lockImplementations {
+		l := impl.newLock()
+		ctx := context.Background()
+		counter := 0
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					l.Lock(ctx)
+					n := counter
+					time.Sleep(time.Microsecond)
+					counter = n + 1
+					l.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if counter != 800 {
+			t.Errorf("%s: counter %d, want 800", impl.name, counter)
+		}
+	}
+}
+
+func TestLocksGiveUpOnContext(t *testing.T) {
+	for _, impl := range lockImplementations {
+		l := impl.newLock()
+		l.Lock(context.Background())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		if err := l.Lock(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: Lock got %v, want DeadlineExceeded", impl.name, err)
+		}
+		if err := l.RLock(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: RLock got %v, want DeadlineExceeded", impl.name, err)
+		}
+		if l.TryRLock() {
+			t.Errorf("%s: TryRLock succeeded while write-locked", impl.name)
+		}
+		cancel()
+
+		// The abandoned attempts must not leave the lock unusable
+		l.Unlock()
+		ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+		if err := l.Lock(ctx); err != nil {
+			t.Errorf("%s: Lock after release got %v", impl.name, err)
+		}
+		cancel()
+	}
+}
+
+func TestResourceCorrectnessAcrossLocks(t *testing.T) {
+	for _, impl := range lockImplementations {
+		r := NewResource("0", WithLock(impl.newLock))
+		ctx := context.Background()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					if _, err := r.Increment(ctx, 1); err != nil {
+						t.Error(err)
+						return
+					}
+					r.Read(ctx)
+				}
+			}()
+		}
+		wg.Wait()
+		if data, _ := r.Read(ctx); data != "400" {
+			t.Errorf("%s: got %s after 400 increments", impl.name, data)
+		}
+
+		err := r.WriteAndDowngrade(ctx, "downgraded", func(data string) error {
+			if data != "downgraded" {
+				return fmt.Errorf("callback saw %q", data)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%s: WriteAndDowngrade: %v", impl.name, err)
+		}
+		if _, err := blockedFor(t, r, func() error {
+			readCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+			defer cancel()
+			_, err := r.Read(readCtx)
+			return err
+		}); err == nil {
+			t.Errorf("%s: read succeeded while the write lock was held", impl.name)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// LockTimeoutError is returned when an operation gives up waiting for the resource's lock.
+// It unwraps to the context error, so errors.Is(err, context.DeadlineExceeded) still holds.
+type LockTimeoutError struct {
+	Op           string        // Operation that timed out, such as OpRead or OpWrite
+	Waited       time.Duration // How long the operation waited for the lock
+	ReadersAhead int           // Readers holding the lock when the wait began
+	WritersAhead int           // Writers holding or waiting for the lock when the wait began
+	Err          error         // Context error that ended the wait
+}
+
+// Error describes the wait and the contention behind it.
+func (e *LockTimeoutError) Error() string {
+	return fmt.Sprintf("%s gave up waiting for the lock after %v with %d readers and %d writers ahead: %v",
+		e.Op, e.Waited, e.ReadersAhead, e.WritersAhead, e.Err)
+}
+
+// Unwrap returns the context error.
+func (e *LockTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// lockInspector is implemented by locks that can report who holds or is waiting for them.
+type lockInspector interface {
+	Holders() (readers, writers int)
+}
+
+// Holders returns the number of readers holding the lock and of writers holding or waiting for it.
+func (l *rwLock) Holders() (readers, writers int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	writers = l.waitingWriters
+	if l.writer {
+		writers++
+	}
+	return l.readers, writers
+}
+
+// lockWait records the contention seen by an operation about to wait for the lock.
+type lockWait struct {
+	op               string
+	start            time.Time
+	readers, writers int
+}
+
+// beginLockWait notes the time and the lock's holders before an operation waits for it.
+func (r *Resource) beginLockWait(op string) lockWait {
+	w := lockWait{op: op, start: time.Now()}
+	if i, ok := r.mu.(lockInspector); ok {
+		w.readers, w.writers = i.Holders()
+	}
+	return w
+}
+
+// fail annotates err, returned by the lock, with the wait's diagnostics.
+func (w lockWait) fail(err error) error {
+	return &LockTimeoutError{
+		Op:           w.op,
+		Waited:       time.Since(w.start),
+		ReadersAhead: w.readers,
+		WritersAhead: w.writers,
+		Err:          err,
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLockTimeoutErrorFields(t *testing.T) {
+	const timeout = 20 * time.Millisecond
+	r := NewResource("initial")
+	ctx := context.Background()
+
+	// Two readers hold the lock and a writer queues behind them
+	for i := 0; i < 2; i++ {
+		_, release, err := r.BeginSnapshot(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer release()
+	}
+	queuedCtx, cancelQueued := context.WithCancel(ctx)
+	defer cancelQueued()
+	go r.Write(queuedCtx, "queued")
+	for r.WaitQueueDepth() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	writeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := r.Write(writeCtx, "late")
+
+	var lockErr *LockTimeoutError
+	if !errors.As(err, &lockErr) {
+		t.Fatalf("got %v, want a *LockTimeoutError", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("LockTimeoutError does not unwrap to DeadlineExceeded")
+	}
+	if lockErr.Op != OpWrite || lockErr.ReadersAhead != 2 || lockErr.WritersAhead != 1 {
+		t.Fatalf("got op %s with %d readers and %d writers ahead, want a write behind 2 readers and 1 writer",
+			lockErr.Op, lockErr.ReadersAhead, lockErr.WritersAhead)
+	}
+	if lockErr.Waited < timeout {
+		t.Fatalf("waited %v, want at least the %v timeout", lockErr.Waited, timeout)
+	}
+	if msg := lockErr.Error(); !strings.Contains(msg, "2 readers and 1 writers ahead") {
+		t.Fatalf("message %q lacks the contention details", msg)
+	}
+}
+
+func TestCanceledBeforeWaitIsPlainContextError(t *testing.T) {
+	r := NewResource("initial")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var lockErr *LockTimeoutError
+	if _, err := r.Read(ctx); errors.As(err, &lockErr) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want a plain context.Canceled for an operation that never waited", err)
+	}
+}
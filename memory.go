@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrMemoryLimit is returned by a write that would push the resource's memory use past its limit.
+var ErrMemoryLimit = errors.New("resource memory limit exceeded")
+
+// WithMaxBytes bounds the memory used by the resource: the stored value plus the values retained
+// by the operation log and the event timeline, if enabled. A write that would exceed max fails
+// with ErrMemoryLimit. The timeline never shrinks, so with it enabled writes stop once the
+// values recorded so far reach the limit.
+func WithMaxBytes(max int64) ResourceOption {
+	return func(r *Resource) {
+		r.maxBytes = max
+	}
+}
+
+// CurrentBytes returns the memory accounted against the limit set by WithMaxBytes.
+func (r *Resource) CurrentBytes() int64 {
+	n := atomic.LoadInt64(&r.valueBytes)
+	if r.opLog != nil {
+		n += atomic.LoadInt64(&r.opLog.bytes)
+	}
+	return n + atomic.LoadInt64(&r.eventsBytes)
+}
+
+// checkMemory reports whether replacing the current data with newData stays within the limit.
+// The write lock must be held.
+func (r *Resource) checkMemory(newData string) error {
+	if r.maxBytes <= 0 {
+		return nil
+	}
+	projected := r.CurrentBytes() - int64(len(r.data)) + int64(len(newData))
+	if r.opLog != nil {
+		projected += int64(len(newData)) // The write's own record will be logged
+	}
+	if atomic.LoadInt32(&r.timeline) == 1 {
+		projected += int64(len(newData)) // And kept in the timeline
+	}
+	if projected > r.maxBytes {
+		return ErrMemoryLimit
+	}
+	return nil
+}
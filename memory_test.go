@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMaxBytesBoundary(t *testing.T) {
+	r := NewResource("", WithMaxBytes(10))
+	ctx := context.Background()
+
+	if err := r.Write(ctx, strings.Repeat("a", 10)); err != nil {
+		t.Fatalf("write exactly at the limit: %v", err)
+	}
+	if err := r.Write(ctx, strings.Repeat("a", 11)); !errors.Is(err, ErrMemoryLimit) {
+		t.Fatalf("write over the limit: got %v, want ErrMemoryLimit", err)
+	}
+	if data, _ := r.Read(ctx); len(data) != 10 {
+		t.Fatalf("rejected write changed the data to %q", data)
+	}
+	if err := r.Write(ctx, "short"); err != nil {
+		t.Fatalf("shrinking write: %v", err)
+	}
+	if got := r.CurrentBytes(); got != 5 {
+		t.Fatalf("CurrentBytes %d, want 5", got)
+	}
+}
+
+func TestMaxBytesCountsOperationLog(t *testing.T) {
+	r := NewResource("", WithMaxBytes(10), WithOperationLog(2))
+	ctx := context.Background()
+
+	if err := r.Write(ctx, "abcd"); err != nil { // 4 stored + 4 logged
+		t.Fatal(err)
+	}
+	if err := r.Write(ctx, "abcde"); !errors.Is(err, ErrMemoryLimit) { // 5 stored + 4 + 5 logged
+		t.Fatalf("got %v, want ErrMemoryLimit", err)
+	}
+}
+
+func TestMaxBytesCountsEventTimeline(t *testing.T) {
+	const max = 1000
+	r := NewResource("", WithMaxBytes(max), WithEventTimeline())
+	ctx := context.Background()
+	value := strings.Repeat("v", 100)
+
+	writes := 0
+	for i := 0; i < 20; i++ {
+		if err := r.Write(ctx, value); err != nil {
+			if !errors.Is(err, ErrMemoryLimit) {
+				t.Fatal(err)
+			}
+			break
+		}
+		writes++
+	}
+	if writes == 0 || writes >= 10 {
+		t.Fatalf("accepted %d writes of 100 bytes, want the timeline to reach the %d byte limit", writes, max)
+	}
+	if got := r.CurrentBytes(); got > max {
+		t.Fatalf("CurrentBytes %d exceeds the limit of %d", got, max)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// counterShards is the number of independent slots in a shardedCounter.
+const counterShards = 32
+
+// shardedCounter is a counter spread across cache-line padded slots.
+// Concurrent updates land on different slots, avoiding contention on a single shared atomic.
+type shardedCounter struct {
+	shards [counterShards]struct {
+		n uint64
+		_ [56]byte // Pad each slot to its own cache line
+	}
+}
+
+// Add adds delta to a randomly chosen slot.
+func (c *shardedCounter) Add(delta uint64) {
+	atomic.AddUint64(&c.shards[rand.Uint32()%counterShards].n, delta)
+}
+
+// Load returns the sum of all slots.
+func (c *shardedCounter) Load() uint64 {
+	var total uint64
+	for i := range c.shards {
+		total += atomic.LoadUint64(&c.shards[i].n)
+	}
+	return total
+}
+
+// shardedFloat is a float64 sum spread across cache-line padded slots, like shardedCounter.
+type shardedFloat struct {
+	shards [counterShards]struct {
+		bits uint64 // math.Float64bits of the slot's sum
+		_    [56]byte
+	}
+}
+
+// Add adds delta to a randomly chosen slot.
+func (f *shardedFloat) Add(delta float64) {
+	slot := &f.shards[rand.Uint32()%counterShards].bits
+	for {
+		old := atomic.LoadUint64(slot)
+		if atomic.CompareAndSwapUint64(slot, old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+// Load returns the sum of all slots.
+func (f *shardedFloat) Load() float64 {
+	var total float64
+	for i := range f.shards {
+		total += math.Float64frombits(atomic.LoadUint64(&f.shards[i].bits))
+	}
+	return total
+}
+
+// Metrics is a snapshot of the operation counters of a resource.
+type Metrics struct {
+	Reads       uint64 // Successful reads
+	Writes      uint64 // Successful writes
+	ReadErrors  uint64 // Reads that returned an error
+	WriteErrors uint64 // Writes that returned an error
+
+	DroppedNotifications uint64 // Updates discarded by subscriber overflow policies
+	DroppedStreamEvents  uint64 // Events discarded because an event stream fell behind
+
+	ReadQueueWait Histogram // Time reads spent waiting for the lock, for sampled reads
+	ReadLatency   Histogram // Total time of reads including the lock wait, for sampled reads
+
+	Fairness LockFairness // How evenly operations were served by the lock
+}
+
+// resourceMetrics holds the live counters behind Metrics.
+type resourceMetrics struct {
+	reads, writes, readErrors, writeErrors shardedCounter
+	droppedNotifications                   shardedCounter
+	droppedStreamEvents                    shardedCounter
+	window                                 successWindow // Recent outcomes for SuccessRate
+	readQueueWait, readLatency             histogram
+	sampler                                *latencySampler // Chooses operations for the histograms; nil samples all
+	waits                                  waitStats       // Lock waits of every operation, for Fairness
+}
+
+// record counts the outcome of a completed operation.
+func (m *resourceMetrics) record(op string, err error) {
+	m.window.record(time.Now(), err)
+	switch {
+	case op == OpRead && err == nil:
+		m.reads.Add(1)
+	case op == OpRead:
+		m.readErrors.Add(1)
+	case err == nil:
+		m.writes.Add(1)
+	default:
+		m.writeErrors.Add(1)
+	}
+}
+
+// readLatency combines the read latency histograms of several resources.
+func readLatency(resources []*Resource) Histogram {
+	var h Histogram
+	for _, r := range resources {
+		h = h.Merge(r.Metrics().ReadLatency)
+	}
+	return h
+}
+
+// Metrics returns a snapshot of the resource's operation counters, aggregated across shards.
+func (r *Resource) Metrics() Metrics {
+	return Metrics{
+		Reads:       r.metrics.reads.Load(),
+		Writes:      r.metrics.writes.Load(),
+		ReadErrors:  r.metrics.readErrors.Load(),
+		WriteErrors: r.metrics.writeErrors.Load(),
+
+		DroppedNotifications: r.metrics.droppedNotifications.Load(),
+		DroppedStreamEvents:  r.metrics.droppedStreamEvents.Load(),
+
+		ReadQueueWait: r.metrics.readQueueWait.snapshot(),
+		ReadLatency:   r.metrics.readLatency.snapshot(),
+
+		Fairness: r.fairness(),
+	}
+}
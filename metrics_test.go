@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestShardedCounterConcurrent(t *testing.T) {
+	var c shardedCounter
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				c.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := c.Load(); got != 8000 {
+		t.Fatalf("got %d, want 8000", got)
+	}
+}
+
+func TestMetricsCountOutcomes(t *testing.T) {
+	r := NewResource("initial")
+	ctx := context.Background()
+	r.Read(ctx)
+	r.Write(ctx, "x")
+	r.SetReadOnly(true)
+	if err := r.Write(ctx, "y"); !errors.Is(err, ErrReadOnly) {
+		t.Fatal(err)
+	}
+
+	m := r.Metrics()
+	if m.Reads != 1 || m.Writes != 1 || m.ReadErrors != 0 || m.WriteErrors != 1 {
+		t.Fatalf("got %+v", m)
+	}
+}
+
+func BenchmarkShardedCounter(b *testing.B) {
+	var c shardedCounter
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Add(1)
+		}
+	})
+}
+
+func BenchmarkSingleAtomicCounter(b *testing.B) {
+	var n uint64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			atomic.AddUint64(&n, 1)
+		}
+	})
+}
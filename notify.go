@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// notifyWrite bumps the version and wakes every goroutine waiting for a change.
+// It is called by every successful write while the write lock is held.
+func (r *Resource) notifyWrite() {
+	r.notifyMu.Lock()
+	defer r.notifyMu.Unlock()
+	r.version++
+	r.lastWrite = time.Now()
+	if id := r.lastWriter.ID; id != 0 { // Anonymous writes are not attributed to a worker
+		if r.writers == nil {
+			r.writers = make(map[int]struct{})
+		}
+		r.writers[id] = struct{}{}
+	}
+	if r.changed != nil {
+		close(r.changed)
+		r.changed = nil
+	}
+}
+
+// watch returns the current version and a channel that is closed on the next write.
+func (r *Resource) watch() (uint64, <-chan struct{}) {
+	r.notifyMu.Lock()
+	defer r.notifyMu.Unlock()
+	if r.changed == nil {
+		r.changed = make(chan struct{})
+	}
+	return r.version, r.changed
+}
+
+// Version returns the number of successful writes applied to the resource.
+func (r *Resource) Version() uint64 {
+	r.notifyMu.Lock()
+	defer r.notifyMu.Unlock()
+	return r.version
+}
+
+// WaitForVersion blocks until the version reaches at least v, or returns the context error.
+// It returns immediately if the version has already been reached.
+func (r *Resource) WaitForVersion(ctx context.Context, v uint64) error {
+	for {
+		version, changed := r.watch()
+		if version >= v {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// WaitForWriters blocks until at least n distinct workers have written successfully, or returns the context error.
+// Workers are told apart by identity ID; writes without an identity are not counted.
+func (r *Resource) WaitForWriters(ctx context.Context, n int) error {
+	for {
+		r.notifyMu.Lock()
+		writers := len(r.writers)
+		if r.changed == nil {
+			r.changed = make(chan struct{})
+		}
+		changed := r.changed
+		r.notifyMu.Unlock()
+		if writers >= n {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// WaitQuiescent blocks until no write has occurred for quietFor, or returns the context error.
+// A resource that has never been written counts as quiet since its creation.
+func (r *Resource) WaitQuiescent(ctx context.Context, quietFor time.Duration) error {
+	for {
+		r.notifyMu.Lock()
+		remaining := quietFor - time.Since(r.lastWrite)
+		if r.changed == nil {
+			r.changed = make(chan struct{})
+		}
+		changed := r.changed
+		r.notifyMu.Unlock()
+		if remaining <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(remaining)
+		select {
+		case <-changed: // A write restarted the quiet window
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+		timer.Stop()
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWaitForVersionAlreadyReached(t *testing.T) {
+	r := NewResource("initial")
+	r.Write(context.Background(), "x")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() // Must not be needed when the version is already there
+	if err := r.WaitForVersion(ctx, 1); err != nil {
+		t.Fatalf("got %v, want an immediate return", err)
+	}
+}
+
+func TestWaitForVersionDelayed(t *testing.T) {
+	r := NewResource("initial")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- r.WaitForVersion(ctx, 2) }()
+	r.Write(ctx, "first")
+	select {
+	case err := <-done:
+		t.Fatalf("returned %v after one write, want it to wait for version 2", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	r.Write(ctx, "second")
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitForVersionTimeout(t *testing.T) {
+	r := NewResource("initial")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := r.WaitForVersion(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestWaitQuiescentAfterWritesStop(t *testing.T) {
+	const quiet = 30 * time.Millisecond
+	r := NewResource("initial")
+	ctx := context.Background()
+
+	stopped := make(chan time.Time, 1)
+	go func() {
+		for i := 0; i < 5; i++ {
+			r.Write(ctx, fmt.Sprint(i))
+			time.Sleep(quiet / 3) // Well within the quiet window
+		}
+		stopped <- time.Now()
+	}()
+
+	waitCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	time.Sleep(time.Millisecond) // Let the first write land
+	if err := r.WaitQuiescent(waitCtx, quiet); err != nil {
+		t.Fatal(err)
+	}
+	returned := time.Now()
+	select {
+	case last := <-stopped:
+		if returned.Sub(last) < quiet-quiet/3 {
+			t.Fatalf("returned %v after the writes stopped, want about %v", returned.Sub(last), quiet)
+		}
+	default:
+		t.Fatal("returned while writes were still happening")
+	}
+}
+
+func TestWaitQuiescentTimeout(t *testing.T) {
+	r := NewResource("initial")
+	r.Write(context.Background(), "x")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := r.WaitQuiescent(ctx, time.Hour); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestWaitForWritersCountsDistinctIdentities(t *testing.T) {
+	r := NewResource("initial")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- r.WaitForWriters(ctx, 3) }()
+	write := func(id int) {
+		r.Write(WithIdentity(context.Background(), WorkerIdentity{ID: id}), fmt.Sprint(id))
+	}
+	write(1)
+	write(1) // The same writer again
+	write(2)
+	r.Write(context.Background(), "anonymous") // Not counted
+	select {
+	case err := <-done:
+		t.Fatalf("returned %v after two distinct writers, want it to wait for a third", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	write(3)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitForWritersTimeout(t *testing.T) {
+	r := NewResource("initial")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := r.WaitForWriters(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestWaitForWritersInSimulation(t *testing.T) {
+	const workers = 3
+	r := NewResource(initialData)
+	errs := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		errs <- r.WaitForWriters(ctx, workers)
+	}()
+	_, err := RunSimulation(workers, 5*time.Second, WithResourceFactory(func(string) *Resource { return r }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("not every simulated worker was seen writing: %v", err)
+	}
+}
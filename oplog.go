@@ -0,0 +1,69 @@
+package main
+
+import "sync/atomic"
+
+// OperationRecord is an operation captured by the operation log.
+type OperationRecord = Event
+
+// WithOperationLog keeps the last n operations in an in-memory ring buffer, exposed via RecentOperations.
+// Memory stays bounded however many operations run, which makes it suitable for post-mortem debugging.
+func WithOperationLog(n int) ResourceOption {
+	return func(r *Resource) {
+		if n > 0 {
+			r.opLog = &operationLog{slots: make([]atomic.Pointer[logSlot], n)}
+		}
+	}
+}
+
+// operationLog is a ring buffer of operation records. Writers claim a sequence number
+// atomically and publish into its slot without taking a lock.
+type operationLog struct {
+	next  uint64 // Sequence number of the next record
+	bytes int64  // Bytes of values held by the retained records
+	slots []atomic.Pointer[logSlot]
+}
+
+// logSlot is one published record and its sequence number.
+type logSlot struct {
+	seq uint64
+	rec OperationRecord
+}
+
+// add appends rec, overwriting the oldest record once the buffer is full.
+func (l *operationLog) add(rec OperationRecord) {
+	seq := atomic.AddUint64(&l.next, 1) - 1
+	old := l.slots[seq%uint64(len(l.slots))].Swap(&logSlot{seq: seq, rec: rec})
+	delta := int64(len(rec.Value))
+	if old != nil {
+		delta -= int64(len(old.rec.Value))
+	}
+	atomic.AddInt64(&l.bytes, delta)
+}
+
+// recent returns the retained records from oldest to newest.
+// Records still being published, or overwritten while reading, are skipped.
+func (l *operationLog) recent() []OperationRecord {
+	end := atomic.LoadUint64(&l.next)
+	start := uint64(0)
+	if n := uint64(len(l.slots)); end > n {
+		start = end - n
+	}
+	records := make([]OperationRecord, 0, end-start)
+	for seq := start; seq < end; seq++ {
+		slot := l.slots[seq%uint64(len(l.slots))].Load()
+		if slot == nil || slot.seq != seq {
+			continue
+		}
+		records = append(records, slot.rec)
+	}
+	return records
+}
+
+// RecentOperations returns the operations retained by the operation log, oldest first.
+// It returns nil if the resource was created without WithOperationLog.
+func (r *Resource) RecentOperations() []OperationRecord {
+	if r.opLog == nil {
+		return nil
+	}
+	return r.opLog.recent()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestOperationLogKeepsLastN(t *testing.T) {
+	const n = 3
+	r := NewResource("initial", WithOperationLog(n))
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		r.Write(ctx, fmt.Sprint(i))
+	}
+
+	recs := r.RecentOperations()
+	if len(recs) != n {
+		t.Fatalf("retained %d records, want %d", len(recs), n)
+	}
+	for i, rec := range recs {
+		if want := fmt.Sprint(i + 2); rec.Op != OpWrite || rec.Value != want {
+			t.Errorf("record %d: got %s %q, want write %q", i, rec.Op, rec.Value, want)
+		}
+	}
+}
+
+func TestOperationLogConcurrent(t *testing.T) {
+	const n = 16
+	r := NewResource("initial", WithOperationLog(n))
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				r.Read(ctx)
+				r.RecentOperations()
+			}
+		}()
+	}
+	wg.Wait()
+	if recs := r.RecentOperations(); len(recs) != n {
+		t.Fatalf("retained %d records, want %d", len(recs), n)
+	}
+}
+
+func TestOperationLogDisabled(t *testing.T) {
+	r := NewResource("initial")
+	r.Read(context.Background())
+	if recs := r.RecentOperations(); recs != nil {
+		t.Fatalf("got %v without an operation log", recs)
+	}
+}
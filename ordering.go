@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// WithKeyOrdering guarantees that writes and deletes of the same key are applied in the order
+// their calls were made, even when issued concurrently, by passing them through a per-key queue.
+// Operations on different keys still run concurrently. Reads and multi-key Merge calls are not queued.
+func WithKeyOrdering() KeyedOption {
+	return func(k *KeyedResource) {
+		k.queues = &keyQueues{tails: make(map[string]chan struct{})}
+	}
+}
+
+// keyQueues serializes operations per key. Each queued operation owns a channel that it closes
+// when it finishes, and waits for the channel of the operation queued before it.
+type keyQueues struct {
+	mu    sync.Mutex
+	tails map[string]chan struct{} // Channel of the last operation queued for each key
+}
+
+// enqueue waits for every earlier operation on key to finish and returns a function that must be
+// called once this operation finishes. If ctx is done first, enqueue returns its error and the
+// operation gives up its place without delaying the operations queued after it.
+func (k *KeyedResource) enqueue(ctx context.Context, key string) (func(), error) {
+	q := k.queues
+	if q == nil {
+		return func() {}, nil
+	}
+
+	q.mu.Lock()
+	prev := q.tails[key]
+	own := make(chan struct{})
+	q.tails[key] = own
+	q.mu.Unlock()
+
+	release := func() {
+		q.mu.Lock()
+		if q.tails[key] == own {
+			delete(q.tails, key) // Nothing queued behind this operation
+		}
+		q.mu.Unlock()
+		close(own)
+	}
+	if prev == nil {
+		return release, nil
+	}
+	select {
+	case <-prev:
+		return release, nil
+	case <-ctx.Done():
+		go func() {
+			<-prev // Keep later operations behind the ones queued before this one
+			release()
+		}()
+		return nil, ctx.Err()
+	}
+}
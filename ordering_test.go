@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tail returns the channel of the last operation queued for key.
+func tail(k *KeyedResource, key string) chan struct{} {
+	k.queues.mu.Lock()
+	defer k.queues.mu.Unlock()
+	return k.queues.tails[key]
+}
+
+func TestKeyOrderingAppliesInSubmissionOrder(t *testing.T) {
+	k := NewKeyedResource(1, WithKeyOrdering())
+	ctx := context.Background()
+
+	// Hold the shard so every write piles up, then submit them one by one from separate goroutines
+	s := k.shard("key")
+	if err := s.mu.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	const writes = 10
+	var wg sync.WaitGroup
+	for i := 0; i < writes; i++ {
+		before := tail(k, "key")
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := k.Write(ctx, "key", fmt.Sprint(i)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+		for tail(k, "key") == before {
+			time.Sleep(time.Millisecond) // Wait until this write has taken its place in the queue
+		}
+	}
+	s.mu.Unlock()
+	wg.Wait()
+
+	if value, _, _ := k.Read(ctx, "key"); value != fmt.Sprint(writes-1) {
+		t.Fatalf("got %q, want the last submitted write", value)
+	}
+}
+
+func TestKeyOrderingKeepsOtherKeysConcurrent(t *testing.T) {
+	k := NewKeyedResource(2, WithHash(byFirstByte), WithKeyOrdering())
+	ctx := context.Background()
+
+	// A write to "a" is stuck on its shard; a write to "b" must not queue behind it
+	s := k.shard("a")
+	if err := s.mu.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	go k.Write(ctx, "a", "stuck")
+	for tail(k, "a") == nil {
+		time.Sleep(time.Millisecond)
+	}
+	writeCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := k.Write(writeCtx, "b", "free"); err != nil {
+		t.Fatalf("write to another key waited: %v", err)
+	}
+	s.mu.Unlock()
+}
+
+func TestKeyOrderingCanceledWaiterKeepsOrder(t *testing.T) {
+	k := NewKeyedResource(1, WithKeyOrdering())
+	ctx := context.Background()
+	s := k.shard("key")
+	if err := s.mu.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	first := make(chan error, 1)
+	go func() { first <- k.Write(ctx, "key", "first") }()
+	for tail(k, "key") == nil {
+		time.Sleep(time.Millisecond)
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := k.Write(canceled, "key", "abandoned"); err != context.Canceled {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	last := make(chan error, 1)
+	before := tail(k, "key")
+	go func() { last <- k.Write(ctx, "key", "last") }()
+	for tail(k, "key") == before {
+		time.Sleep(time.Millisecond)
+	}
+
+	s.mu.Unlock()
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-last; err != nil {
+		t.Fatal(err)
+	}
+	if value, _, _ := k.Read(ctx, "key"); value != "last" {
+		t.Fatalf("got %q, want the last write applied after the first", value)
+	}
+}
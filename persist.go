@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteAndPersist replaces the data with the value computed by fn from the current data, as Read
+// would return it, and saves it to path, both under the write lock, so the file never lags the
+// in-memory value written by this call. The file is written to a temporary file and renamed into
+// place, so it always holds either the old or the new value. If saving fails, the in-memory change
+// is rolled back and the error returned.
+func (r *Resource) WriteAndPersist(ctx context.Context, fn func(current string) (string, error), path string) error {
+	return r.updateThen(ctx, r.visible(fn), func(newData string) error {
+		return saveFile(path, newData)
+	})
+}
+
+// saveFile atomically replaces the contents of path with data.
+func saveFile(path, data string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("persisting to %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name()) // Fails harmlessly once the file has been renamed
+
+	if _, err := tmp.WriteString(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("persisting to %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("persisting to %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("persisting to %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("persisting to %s: %w", path, err)
+	}
+	return nil
+}
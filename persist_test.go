@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteAndPersist(t *testing.T) {
+	r := NewResource("initial")
+	path := filepath.Join(t.TempDir(), "state")
+	appendX := func(current string) (string, error) { return current + "-x", nil }
+
+	for _, want := range []string{"initial-x", "initial-x-x"} {
+		if err := r.WriteAndPersist(context.Background(), appendX, path); err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := r.Read(context.Background()); data != want {
+			t.Fatalf("stored %q, want %q", data, want)
+		}
+		if saved, err := os.ReadFile(path); err != nil || string(saved) != want {
+			t.Fatalf("saved %q (%v), want %q", saved, err, want)
+		}
+	}
+}
+
+func TestWriteAndPersistRollsBackFailedSave(t *testing.T) {
+	r := NewResource("initial")
+	dir := t.TempDir()
+	for _, path := range []string{
+		filepath.Join(dir, "missing", "state"), // The temporary file cannot be created
+		dir,                                    // The temporary file cannot be renamed over a directory
+	} {
+		err := r.WriteAndPersist(context.Background(), func(string) (string, error) { return "lost", nil }, path)
+		if err == nil {
+			t.Fatalf("saving to %s: got no error", path)
+		}
+		if data, _ := r.Read(context.Background()); data != "initial" {
+			t.Fatalf("saving to %s failed but stored %q, want the write rolled back", path, data)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("left %d temporary files behind", len(entries))
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WorkerPool runs a job repeatedly on each of a set of workers until it is stopped.
+type WorkerPool struct {
+	// ShutdownTimeout is how long Stop lets running jobs finish before canceling them.
+	// Zero cancels them immediately.
+	ShutdownTimeout time.Duration
+
+	workers  []*Worker
+	draining chan struct{} // Closed by Stop so workers start no further jobs
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[*Worker]context.CancelFunc // Cancels each worker that has not exited yet
+}
+
+// NewWorkerPool creates a new WorkerPool over workers.
+func NewWorkerPool(workers ...*Worker) *WorkerPool {
+	return &WorkerPool{
+		workers:  workers,
+		draining: make(chan struct{}),
+		running:  make(map[*Worker]context.CancelFunc),
+	}
+}
+
+// Start runs job in a loop on every worker until Stop is called. The ctx passed to job is
+// canceled if the job is still running when Stop's shutdown timeout expires.
+func (p *WorkerPool) Start(job func(ctx context.Context, w *Worker)) {
+	for _, w := range p.workers {
+		ctx, cancel := context.WithCancel(context.Background())
+		p.mu.Lock()
+		p.running[w] = cancel
+		p.mu.Unlock()
+
+		p.wg.Add(1)
+		go func(w *Worker) {
+			defer p.wg.Done()
+			defer p.exited(w)
+			for {
+				select {
+				case <-p.draining:
+					return
+				default:
+					job(ctx, w)
+				}
+			}
+		}(w)
+	}
+}
+
+// exited records that w has stopped running jobs.
+func (p *WorkerPool) exited(w *Worker) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cancel, ok := p.running[w]; ok {
+		cancel()
+		delete(p.running, w)
+	}
+}
+
+// Stop drains the pool: workers finish their current job and start no more. Workers still
+// running after ShutdownTimeout have their contexts canceled. Stop waits for every worker to
+// exit and returns the identities of those that had to be canceled, ordered by ID.
+func (p *WorkerPool) Stop() (forced []WorkerIdentity) {
+	p.stopOnce.Do(func() { close(p.draining) })
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(p.ShutdownTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+	}
+
+	// The grace period is over, so cancel whoever is left
+	p.mu.Lock()
+	for w, cancel := range p.running {
+		fmt.Printf("%s: Forcefully canceled after %v shutdown timeout\n", w.Identity, p.ShutdownTimeout)
+		forced = append(forced, w.Identity)
+		cancel()
+	}
+	p.mu.Unlock()
+	<-done
+
+	sort.Slice(forced, func(i, j int) bool {
+		return forced[i].ID < forced[j].ID
+	})
+	return forced
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolForceCancelsStuckWorker(t *testing.T) {
+	r := NewResource("initial")
+	quick := NewWorker(WorkerIdentity{ID: 1}, r)
+	stuck := NewWorker(WorkerIdentity{ID: 2}, r)
+	p := NewWorkerPool(quick, stuck)
+	p.ShutdownTimeout = 20 * time.Millisecond
+
+	canceled := make(chan error, 1)
+	started := make(chan struct{})
+	p.Start(func(ctx context.Context, w *Worker) {
+		if w != stuck {
+			time.Sleep(time.Millisecond)
+			return
+		}
+		close(started)
+		<-ctx.Done() // Ignores draining and only stops when canceled
+		canceled <- ctx.Err()
+	})
+	<-started
+
+	start := time.Now()
+	var forced []WorkerIdentity
+	output := captureOutput(t, func() { forced = p.Stop() })
+	elapsed := time.Since(start)
+
+	if fmt.Sprint(forced) != "[Worker 2]" {
+		t.Fatalf("force-canceled %v, want only the stuck worker", forced)
+	}
+	if elapsed < p.ShutdownTimeout {
+		t.Fatalf("Stop canceled after %v, before the %v grace period", elapsed, p.ShutdownTimeout)
+	}
+	if err := <-canceled; err != context.Canceled {
+		t.Fatalf("stuck job saw %v, want context.Canceled", err)
+	}
+	if output == "" {
+		t.Fatal("forced cancellation was not logged")
+	}
+}
+
+func TestWorkerPoolGracefulStop(t *testing.T) {
+	p := NewWorkerPool(NewWorker(WorkerIdentity{ID: 1}, NewResource("initial")))
+	p.ShutdownTimeout = time.Second
+	p.Start(func(ctx context.Context, w *Worker) {
+		time.Sleep(time.Millisecond)
+	})
+	if forced := p.Stop(); forced != nil {
+		t.Fatalf("force-canceled %v, want every worker drained within the grace period", forced)
+	}
+}
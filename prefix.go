@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"strings"
+)
+
+// ListPrefix returns every entry whose key starts with prefix, such as all tokens of one user.
+// All shards are read-locked together, so the listing is atomic with respect to single-key operations.
+func (k *KeyedResource) ListPrefix(ctx context.Context, prefix string) (map[string]string, error) {
+	entries := make(map[string]string)
+	err := k.Range(ctx, func(key, value string) bool {
+		if strings.HasPrefix(key, prefix) {
+			entries[key] = value
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// DeletePrefix removes every entry whose key starts with prefix and returns how many were removed.
+// All shards are write-locked together, so no single-key operation observes a partial deletion.
+func (k *KeyedResource) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err() // Return error if context is canceled
+	default:
+		if err := k.Flush(ctx); err != nil { // Buffered writes under the prefix must be deleted too
+			return 0, err
+		}
+		if err := k.lockAll(ctx); err != nil {
+			return 0, err
+		}
+		defer k.unlockAll()
+
+		removed := 0
+		for _, s := range k.shards {
+			for key := range s.data {
+				if !strings.HasPrefix(key, prefix) {
+					continue
+				}
+				if _, ok := s.get(key); ok {
+					removed++ // Expired entries are dropped without being counted
+				}
+				s.remove(key)
+				if k.order != nil {
+					k.order.remove(key)
+				}
+			}
+		}
+		return removed, nil
+	}
+}
+
+// lockAll write-locks every shard in index order, releasing any already held if ctx is canceled.
+func (k *KeyedResource) lockAll(ctx context.Context) error {
+	for i, s := range k.shards {
+		if err := s.mu.Lock(ctx); err != nil {
+			for _, held := range k.shards[:i] {
+				held.mu.Unlock()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// unlockAll releases the write locks taken by lockAll.
+func (k *KeyedResource) unlockAll() {
+	for _, s := range k.shards {
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPrefixOperations(t *testing.T) {
+	k := NewKeyedResource(4, WithInitialData(map[string]string{
+		"user/alice/token1": "a1",
+		"user/alice/token2": "a2",
+		"user/alicia/token": "x",
+		"user/bob/token1":   "b1",
+	}))
+	ctx := context.Background()
+
+	listed, err := k.ListPrefix(ctx, "user/alice/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"user/alice/token1": "a1", "user/alice/token2": "a2"}
+	if fmt.Sprint(listed) != fmt.Sprint(want) {
+		t.Fatalf("listed %v, want %v", listed, want)
+	}
+
+	removed, err := k.DeletePrefix(ctx, "user/alice/")
+	if err != nil || removed != 2 {
+		t.Fatalf("removed %d (%v), want 2", removed, err)
+	}
+	for key := range want {
+		if _, ok, _ := k.Read(ctx, key); ok {
+			t.Errorf("key %q survived DeletePrefix", key)
+		}
+	}
+	for _, key := range []string{"user/alicia/token", "user/bob/token1"} {
+		if _, ok, _ := k.Read(ctx, key); !ok {
+			t.Errorf("key %q outside the prefix was removed", key)
+		}
+	}
+}
+
+func TestDeletePrefixIncludesBufferedWrites(t *testing.T) {
+	k := NewKeyedResource(4, WithWriteCoalescing(time.Hour))
+	ctx := context.Background()
+	k.Write(ctx, "user/alice/token", "pending")
+
+	if removed, err := k.DeletePrefix(ctx, "user/"); err != nil || removed != 1 {
+		t.Fatalf("removed %d (%v), want the buffered write deleted too", removed, err)
+	}
+	if _, ok, _ := k.Read(ctx, "user/alice/token"); ok {
+		t.Fatal("buffered write survived DeletePrefix")
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// WithPriorityQueue services operations submitted with ReadWithPriority and WriteWithPriority
+// one at a time through a dedicated serializer, highest priority first. Operations of equal
+// priority run in submission order. The serializer is stopped when the resource is closed.
+func WithPriorityQueue() ResourceOption {
+	return func(r *Resource) {
+		r.priority = &priorityQueue{}
+	}
+}
+
+// priorityQueue holds operations waiting for the serializer.
+type priorityQueue struct {
+	mu      sync.Mutex
+	pending priorityHeap
+	nextSeq uint64        // Submission order, used to break ties between equal priorities
+	wake    chan struct{} // Signals the serializer that an operation was queued
+	stop    chan struct{} // Closed when the resource is closed
+}
+
+// Priority operation states.
+const (
+	priorityQueued int32 = iota
+	priorityRunning
+	priorityAbandoned
+)
+
+// priorityOp is an operation waiting in a priorityQueue.
+type priorityOp struct {
+	priority int
+	seq      uint64
+	state    int32 // One of priorityQueued, priorityRunning or priorityAbandoned
+	run      func() error
+	err      error
+	done     chan struct{} // Closed once run has returned or the queue has stopped
+}
+
+// priorityHeap orders operations highest priority first, then by submission order.
+type priorityHeap []*priorityOp
+
+func (h priorityHeap) Len() int { return len(h) }
+func (h priorityHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h priorityHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *priorityHeap) Push(x any)   { *h = append(*h, x.(*priorityOp)) }
+func (h *priorityHeap) Pop() any {
+	old := *h
+	op := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return op
+}
+
+// startPriorityQueue starts the serializer and stops it when the resource is closed.
+func (r *Resource) startPriorityQueue() {
+	q := r.priority
+	q.wake = make(chan struct{}, 1)
+	q.stop = make(chan struct{})
+	go q.serve()
+	r.OnClose(func(context.Context) error {
+		close(q.stop)
+		return nil
+	})
+}
+
+// serve runs queued operations one at a time until the queue is stopped.
+func (q *priorityQueue) serve() {
+	for {
+		select {
+		case <-q.wake:
+		case <-q.stop:
+			q.fail(ErrClosed)
+			return
+		}
+		for {
+			q.mu.Lock()
+			if q.pending.Len() == 0 {
+				q.mu.Unlock()
+				break
+			}
+			op := heap.Pop(&q.pending).(*priorityOp)
+			q.mu.Unlock()
+
+			if !atomic.CompareAndSwapInt32(&op.state, priorityQueued, priorityRunning) {
+				continue // The caller gave up while the operation was queued
+			}
+			op.err = op.run()
+			close(op.done)
+		}
+	}
+}
+
+// fail completes every queued operation with err.
+func (q *priorityQueue) fail(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for q.pending.Len() > 0 {
+		op := heap.Pop(&q.pending).(*priorityOp)
+		if atomic.CompareAndSwapInt32(&op.state, priorityQueued, priorityRunning) {
+			op.err = err
+			close(op.done)
+		}
+	}
+}
+
+// submit queues run at priority and waits for the serializer to run it. If ctx is done while
+// the operation is still queued, it is abandoned and submit returns the context error.
+func (q *priorityQueue) submit(ctx context.Context, priority int, run func() error) error {
+	op := &priorityOp{priority: priority, run: run, done: make(chan struct{})}
+	q.mu.Lock()
+	op.seq = q.nextSeq
+	q.nextSeq++
+	heap.Push(&q.pending, op)
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default: // The serializer has already been signaled
+	}
+
+	select {
+	case <-op.done:
+		return op.err
+	case <-ctx.Done():
+		if atomic.CompareAndSwapInt32(&op.state, priorityQueued, priorityAbandoned) {
+			return ctx.Err() // Return error if context is canceled
+		}
+		<-op.done // Already running, so wait for its outcome
+		return op.err
+	case <-q.stop:
+		if atomic.CompareAndSwapInt32(&op.state, priorityQueued, priorityAbandoned) {
+			return ErrClosed // Queued after the serializer stopped
+		}
+		<-op.done
+		return op.err
+	}
+}
+
+// ReadWithPriority reads the data through the priority queue, if one is configured.
+// Higher priorities are served first when operations are waiting; without a priority
+// queue it behaves like Read.
+func (r *Resource) ReadWithPriority(ctx context.Context, priority int) (string, error) {
+	if r.priority == nil {
+		return r.Read(ctx)
+	}
+	if err := r.checkOpen(); err != nil {
+		return "", err
+	}
+	var data string
+	err := r.priority.submit(ctx, priority, func() error {
+		var err error
+		data, err = r.Read(ctx)
+		return err
+	})
+	return data, err
+}
+
+// WriteWithPriority writes newData through the priority queue, if one is configured.
+// Higher priorities are served first when operations are waiting; without a priority
+// queue it behaves like Write.
+func (r *Resource) WriteWithPriority(ctx context.Context, priority int, newData string) error {
+	if r.priority == nil {
+		return r.Write(ctx, newData)
+	}
+	if err := r.checkOpen(); err != nil {
+		return err
+	}
+	return r.priority.submit(ctx, priority, func() error {
+		return r.Write(ctx, newData)
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// queued returns the number of operations waiting in r's priority queue.
+func queued(r *Resource) int {
+	r.priority.mu.Lock()
+	defer r.priority.mu.Unlock()
+	return r.priority.pending.Len()
+}
+
+func TestPriorityQueueServesHighestFirst(t *testing.T) {
+	r := NewResource("initial", WithPriorityQueue())
+	defer r.Close(context.Background())
+	ctx := context.Background()
+	sub := r.Subscribe(SubscribeOptions{Buffer: 8})
+
+	tx, err := r.Begin(ctx) // Contend the lock so the serializer stalls on its first operation
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error, 5)
+	go func() { errs <- r.WriteWithPriority(ctx, 0, "blocker") }()
+	for r.WaitQueueDepth() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for i, priority := range []int{1, 5, 3, 5} {
+		go func(priority int) {
+			errs <- r.WriteWithPriority(ctx, priority, fmt.Sprintf("p%d", priority))
+		}(priority)
+		for queued(r) != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	tx.Rollback()
+	for i := 0; i < 5; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := sub.DrainNotifications(DrainAll)
+	if want := "[blocker p5 p5 p3 p1]"; fmt.Sprint(got) != want {
+		t.Fatalf("writes applied in order %v, want %s", got, want)
+	}
+}
+
+func TestPriorityQueueAbandonedOperation(t *testing.T) {
+	r := NewResource("initial", WithPriorityQueue())
+	defer r.Close(context.Background())
+	tx, err := r.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	go r.WriteWithPriority(context.Background(), 0, "blocker")
+	for r.WaitQueueDepth() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := r.WriteWithPriority(ctx, 9, "abandoned"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want DeadlineExceeded", err)
+	}
+	tx.Rollback()
+	if data, err := r.ReadWithPriority(context.Background(), 0); err != nil || data != "blocker" {
+		t.Fatalf("got %q (%v), want the abandoned write skipped", data, err)
+	}
+}
+
+func TestPriorityQueueClosed(t *testing.T) {
+	r := NewResource("initial", WithPriorityQueue())
+	r.Close(context.Background())
+	if err := r.WriteWithPriority(context.Background(), 1, "late"); !errors.Is(err, ErrClosed) {
+		t.Fatalf("got %v, want ErrClosed", err)
+	}
+}
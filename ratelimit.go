@@ -0,0 +1,151 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ClientIDHeader identifies the client for rate limiting when WithTrustedClientID is set.
+const ClientIDHeader = "X-Client-ID"
+
+// idleBucketTTL is how long a client's bucket may sit unused before it is discarded.
+const idleBucketTTL = 5 * time.Minute
+
+// defaultMaxBuckets bounds the number of clients tracked unless WithMaxClients is used.
+const defaultMaxBuckets = 10000
+
+// RateLimiter is HTTP middleware enforcing a token-bucket rate limit per client.
+type RateLimiter struct {
+	rate          float64 // Tokens added per second
+	burst         float64 // Maximum tokens a bucket can hold
+	trustClientID bool    // Key clients by ClientIDHeader instead of their IP
+	maxBuckets    int     // Most clients tracked at once
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time // Last time idle buckets were discarded
+}
+
+// tokenBucket holds the remaining allowance of one client.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time // Last time tokens were replenished
+}
+
+// RateLimiterOption configures optional behavior of a RateLimiter.
+type RateLimiterOption func(*RateLimiter)
+
+// WithTrustedClientID keys clients by ClientIDHeader, falling back to the IP for requests without it.
+// Clients can pick any header value, so only enable this behind a proxy that sets the header itself.
+func WithTrustedClientID() RateLimiterOption {
+	return func(l *RateLimiter) {
+		l.trustClientID = true
+	}
+}
+
+// WithMaxClients bounds how many clients are tracked at once. Once the limit is reached,
+// the least recently seen client is forgotten to make room for a new one.
+func WithMaxClients(n int) RateLimiterOption {
+	return func(l *RateLimiter) {
+		if n > 0 {
+			l.maxBuckets = n
+		}
+	}
+}
+
+// NewRateLimiter creates a new RateLimiter allowing each client rate requests per second, with bursts up to burst.
+// Clients are keyed by IP unless WithTrustedClientID is set.
+func NewRateLimiter(rate float64, burst int, opts ...RateLimiterOption) *RateLimiter {
+	l := &RateLimiter{
+		rate:       rate,
+		burst:      float64(burst),
+		maxBuckets: defaultMaxBuckets,
+		buckets:    make(map[string]*tokenBucket),
+		lastSweep:  time.Now(),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Middleware wraps next, rejecting requests over the client's limit with 429 and a Retry-After header.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if wait, ok := l.allow(l.clientKey(req), time.Now()); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// allow takes a token from key's bucket. If none is available it returns how long until one will be.
+func (l *RateLimiter) allow(key string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= l.maxBuckets {
+			l.evictOldest()
+		}
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		if wait < time.Second {
+			wait = time.Second // Retry-After has one-second granularity
+		}
+		return wait, false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// sweep discards buckets left idle for longer than idleBucketTTL. It runs at most once per TTL.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleBucketTTL {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= idleBucketTTL {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// evictOldest discards the least recently used bucket. l.mu must be held.
+func (l *RateLimiter) evictOldest() {
+	var oldestKey string
+	var oldest time.Time
+	for key, b := range l.buckets {
+		if oldestKey == "" || b.last.Before(oldest) {
+			oldestKey, oldest = key, b.last
+		}
+	}
+	delete(l.buckets, oldestKey)
+}
+
+// clientKey identifies the client behind req by its IP, or by its identity header if that is trusted.
+func (l *RateLimiter) clientKey(req *http.Request) string {
+	if id := req.Header.Get(ClientIDHeader); l.trustClientID && id != "" {
+		return "id:" + id
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// okHandler answers every request with 200.
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})
+
+// rateLimitedStatus sends a GET from remoteAddr, with clientID in ClientIDHeader if non-empty.
+func rateLimitedStatus(h http.Handler, remoteAddr, clientID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/resource", nil)
+	req.RemoteAddr = remoteAddr
+	if clientID != "" {
+		req.Header.Set(ClientIDHeader, clientID)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimiterRejectsOverLimit(t *testing.T) {
+	h := NewRateLimiter(0.5, 2).Middleware(okHandler)
+
+	for i := 0; i < 2; i++ {
+		if rec := rateLimitedStatus(h, "10.0.0.1:1000", ""); rec.Code != http.StatusOK {
+			t.Fatalf("request %d within the burst: got %d", i, rec.Code)
+		}
+	}
+	rec := rateLimitedStatus(h, "10.0.0.1:1000", "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over the limit: got %d, want 429", rec.Code)
+	}
+	retry, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if err != nil || retry < 1 || retry > 2 {
+		t.Fatalf("Retry-After %q, want 1 or 2 seconds at 0.5 requests per second", rec.Header().Get("Retry-After"))
+	}
+
+	if rec := rateLimitedStatus(h, "10.0.0.2:1000", ""); rec.Code != http.StatusOK {
+		t.Fatalf("another client: got %d, want its own allowance", rec.Code)
+	}
+}
+
+func TestRateLimiterIgnoresClientIDByDefault(t *testing.T) {
+	h := NewRateLimiter(0.5, 1).Middleware(okHandler)
+
+	rateLimitedStatus(h, "10.0.0.1:1000", "a")
+	if rec := rateLimitedStatus(h, "10.0.0.1:1000", "b"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("changing the client ID header: got %d, want 429", rec.Code)
+	}
+}
+
+func TestRateLimiterTrustedClientID(t *testing.T) {
+	h := NewRateLimiter(0.5, 1, WithTrustedClientID()).Middleware(okHandler)
+
+	rateLimitedStatus(h, "10.0.0.1:1000", "a")
+	if rec := rateLimitedStatus(h, "10.0.0.1:1000", "b"); rec.Code != http.StatusOK {
+		t.Fatalf("a different trusted client behind the same proxy: got %d, want 200", rec.Code)
+	}
+	if rec := rateLimitedStatus(h, "10.0.0.1:1000", "a"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("the first trusted client again: got %d, want 429", rec.Code)
+	}
+}
+
+func TestRateLimiterBoundsTrackedClients(t *testing.T) {
+	l := NewRateLimiter(1, 1, WithMaxClients(2))
+	now := time.Now()
+
+	l.allow("a", now)
+	l.allow("b", now.Add(time.Millisecond))
+	l.allow("c", now.Add(2*time.Millisecond))
+	if len(l.buckets) != 2 {
+		t.Fatalf("tracking %d clients, want the cap of 2", len(l.buckets))
+	}
+	if _, ok := l.buckets["a"]; ok {
+		t.Fatal("the least recently seen client was kept")
+	}
+}
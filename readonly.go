@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrReadOnly is returned by writes while the resource is in read-only mode.
+var ErrReadOnly = errors.New("resource is read-only")
+
+// SetReadOnly enables or disables read-only mode. While enabled, writes fail with ErrReadOnly
+// without taking the lock, and reads continue to work. It is safe to call concurrently.
+func (r *Resource) SetReadOnly(ro bool) {
+	var v int32
+	if ro {
+		v = 1
+	}
+	r.modeMu.Lock()
+	defer r.modeMu.Unlock()
+	atomic.StoreInt32(&r.readOnly, v)
+	r.broadcastMode()
+}
+
+// WaitWritable blocks until read-only mode is cleared, or returns the context error.
+// It returns immediately if the resource is writable, and ErrClosed once the resource is closed.
+func (r *Resource) WaitWritable(ctx context.Context) error {
+	for {
+		r.modeMu.Lock()
+		if err := r.checkOpen(); err != nil {
+			r.modeMu.Unlock()
+			return err
+		}
+		if !r.ReadOnly() {
+			r.modeMu.Unlock()
+			return nil
+		}
+		if r.modeChanged == nil {
+			r.modeChanged = make(chan struct{})
+		}
+		changed := r.modeChanged
+		r.modeMu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// broadcastMode wakes every goroutine in WaitWritable. r.modeMu must be held.
+func (r *Resource) broadcastMode() {
+	if r.modeChanged != nil {
+		close(r.modeChanged)
+		r.modeChanged = nil
+	}
+}
+
+// ReadOnly reports whether the resource is in read-only mode.
+func (r *Resource) ReadOnly() bool {
+	return atomic.LoadInt32(&r.readOnly) == 1
+}
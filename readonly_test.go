@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReadOnlyMode(t *testing.T) {
+	r := NewResource("initial")
+	ctx := context.Background()
+
+	r.SetReadOnly(true)
+	if !r.ReadOnly() {
+		t.Fatal("read-only mode not reported")
+	}
+	if err := r.Write(ctx, "blocked"); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("write got %v, want ErrReadOnly", err)
+	}
+	if data, err := r.Read(ctx); err != nil || data != "initial" {
+		t.Fatalf("read in read-only mode: got %q (%v)", data, err)
+	}
+
+	r.SetReadOnly(false)
+	if err := r.Write(ctx, "allowed"); err != nil {
+		t.Fatalf("write after leaving read-only mode: %v", err)
+	}
+	if data, _ := r.Read(ctx); data != "allowed" {
+		t.Fatalf("got %q", data)
+	}
+}
+
+func TestReadOnlyWriteSkipsLock(t *testing.T) {
+	r := NewResource("initial")
+
+	// Hold the write lock, then switch modes: the write must fail at once instead of queueing
+	elapsed, err := blockedFor(t, r, func() error {
+		r.SetReadOnly(true)
+		return r.Write(context.Background(), "blocked")
+	})
+	if !errors.Is(err, ErrReadOnly) || elapsed > 100*time.Millisecond {
+		t.Fatalf("got %v after %v, want ErrReadOnly without waiting for the lock", err, elapsed)
+	}
+}
+
+func TestWaitWritableUnblocksWhenCleared(t *testing.T) {
+	r := NewResource("initial")
+	r.SetReadOnly(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- r.WaitWritable(ctx) }()
+	select {
+	case err := <-done:
+		t.Fatalf("returned %v while still read-only", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	r.SetReadOnly(false)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Write(ctx, "resumed"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitWritableImmediateTimeoutAndClose(t *testing.T) {
+	r := NewResource("initial")
+	if err := r.WaitWritable(context.Background()); err != nil {
+		t.Fatalf("writable resource: got %v", err)
+	}
+
+	r.SetReadOnly(true)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := r.WaitWritable(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want DeadlineExceeded", err)
+	}
+
+	r.Close(context.Background())
+	if err := r.WaitWritable(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Fatalf("got %v, want ErrClosed", err)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// ReplayLog re-executes the operations of a recorded log, in order, against a fresh resource,
+// reproducing the recorded run. Each write stores the value it originally stored, so updates
+// such as appends or increments replay exactly. Operations that failed when recorded are
+// counted as failures again but not re-executed, since they never changed the resource.
+func ReplayLog(ctx context.Context, log []OperationRecord) (SimulationResult, error) {
+	resource := NewResource(initialData, WithEventTimeline())
+	result := newSimulationResult(1)
+
+	for i, rec := range log {
+		if rec.Err != nil {
+			result.Failures++
+			result.Resources[0].Failures++
+			continue
+		}
+
+		opCtx := WithIdentity(ctx, rec.Worker)
+		switch rec.Op {
+		case OpRead:
+			_, err := resource.Read(opCtx)
+			result.recordRead(0, err)
+			if err != nil {
+				return result, fmt.Errorf("replaying operation %d: %w", i, err)
+			}
+		case OpWrite:
+			err := resource.Write(opCtx, rec.Value)
+			result.recordWrite(0, rec.Worker.ID, err)
+			if err != nil {
+				return result, fmt.Errorf("replaying operation %d: %w", i, err)
+			}
+		default:
+			return result, fmt.Errorf("replaying operation %d: unknown operation %q", i, rec.Op)
+		}
+	}
+
+	result.Events = resource.Events()
+	data, err := resource.Read(ctx)
+	if err != nil {
+		return result, err
+	}
+	result.FinalValue = data
+	result.Resources[0].FinalValue = data
+	return result, nil
+}
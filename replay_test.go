@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestReplayLogReproducesSimulation(t *testing.T) {
+	recorded, err := RunSimulation(3, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, err := ReplayLog(context.Background(), recorded.Events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.FinalValue != recorded.FinalValue {
+		t.Fatalf("replay ended with %q, want %q", replayed.FinalValue, recorded.FinalValue)
+	}
+	if fmt.Sprint(replayed.WriteOrder) != fmt.Sprint(recorded.WriteOrder) {
+		t.Fatalf("replay write order %v, want %v", replayed.WriteOrder, recorded.WriteOrder)
+	}
+	if replayed.Reads != recorded.Reads || replayed.Writes != recorded.Writes {
+		t.Fatalf("replay did %d reads and %d writes, want %d and %d", replayed.Reads, replayed.Writes, recorded.Reads, recorded.Writes)
+	}
+}
+
+func TestReplayLogFromOperationLog(t *testing.T) {
+	r := NewResource("", WithOperationLog(16))
+	ctx := WithIdentity(context.Background(), WorkerIdentity{ID: 7})
+	r.Write(ctx, "a")
+	r.AppendLine(ctx, "b") // Replays as a write of the stored value
+	r.Increment(ctx, 1)    // Fails: the value is not numeric
+
+	replayed, err := ReplayLog(context.Background(), r.RecentOperations())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := r.Read(context.Background())
+	if replayed.FinalValue != want {
+		t.Fatalf("replay ended with %q, want %q", replayed.FinalValue, want)
+	}
+	if replayed.Failures != 1 || fmt.Sprint(replayed.WriteOrder) != "[7 7]" {
+		t.Fatalf("got %d failures and write order %v", replayed.Failures, replayed.WriteOrder)
+	}
+}
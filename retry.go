@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// WithRetryBudget retries failed worker operations from a budget of n retries shared by every worker.
+// Once the budget is exhausted, operations fail on their first error instead of retrying,
+// so a burst of failures cannot turn into a retry storm. Retries spent are reported in SimulationResult.Retries.
+func WithRetryBudget(n int) SimulationOption {
+	return func(c *simulationConfig) {
+		c.retryBudget = n
+	}
+}
+
+// retryBudget is a retry allowance shared across workers. A nil budget allows no retries.
+type retryBudget struct {
+	total     int64
+	remaining int64
+}
+
+// newRetryBudget creates a budget of n retries, or a nil budget if n is not positive.
+func newRetryBudget(n int) *retryBudget {
+	if n <= 0 {
+		return nil
+	}
+	return &retryBudget{total: int64(n), remaining: int64(n)}
+}
+
+// take claims one retry, reporting false once the budget is exhausted.
+func (b *retryBudget) take() bool {
+	if b == nil {
+		return false
+	}
+	for {
+		n := atomic.LoadInt64(&b.remaining)
+		if n <= 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&b.remaining, n, n-1) {
+			return true
+		}
+	}
+}
+
+// spent returns the number of retries claimed so far.
+func (b *retryBudget) spent() int {
+	if b == nil {
+		return 0
+	}
+	return int(b.total - atomic.LoadInt64(&b.remaining))
+}
+
+// WithAttemptDeadline bounds each worker operation, including its retries, by total and allows
+// it at most attempts attempts. Each attempt's timeout is the time remaining divided by the attempts
+// remaining, so the last attempt gets whatever is left and the total time spent stays within total.
+// Retries also draw from the budget set by WithRetryBudget, if any.
+func WithAttemptDeadline(total time.Duration, attempts int) SimulationOption {
+	return func(c *simulationConfig) {
+		c.attemptDeadline = total
+		c.maxAttempts = attempts
+	}
+}
+
+// retryPolicy decides whether and for how long failed worker operations are retried.
+type retryPolicy struct {
+	budget   *retryBudget  // Shared allowance; nil allows retries only when attempts is set
+	attempts int           // Attempts allowed per operation; zero means limited only by the budget
+	deadline time.Duration // Time allowed per operation across all its attempts; zero means unbounded
+}
+
+// retryable reports whether a failed attempt may be retried.
+func (p retryPolicy) retryable(attempt int, err error) bool {
+	if errors.Is(err, ErrForbidden) || errors.Is(err, ErrInsufficientTime) {
+		return false // Retrying cannot help
+	}
+	if p.attempts > 0 && attempt+1 >= p.attempts {
+		return false
+	}
+	if p.budget != nil {
+		return p.budget.take()
+	}
+	return p.attempts > 0
+}
+
+// run runs op, retrying it while it fails, ctx is not done, and the policy allows.
+// Each attempt is given its share of the time remaining in the operation's deadline.
+func (p retryPolicy) run(ctx context.Context, op func(ctx context.Context) error) error {
+	if p.deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.deadline)
+		defer cancel()
+	}
+	for attempt := 0; ; attempt++ {
+		err := p.attempt(ctx, attempt, op)
+		if err == nil || ctx.Err() != nil || !p.retryable(attempt, err) {
+			return err
+		}
+	}
+}
+
+// attempt runs op once, bounded by an equal split of the deadline among the remaining attempts.
+func (p retryPolicy) attempt(ctx context.Context, attempt int, op func(ctx context.Context) error) error {
+	deadline, ok := ctx.Deadline()
+	if p.deadline <= 0 || p.attempts <= 0 || !ok {
+		return op(ctx)
+	}
+	share := time.Until(deadline) / time.Duration(p.attempts-attempt)
+	ctx, cancel := context.WithTimeout(ctx, share)
+	defer cancel()
+	return op(ctx)
+}
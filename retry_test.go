@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryBudgetConcurrentTake(t *testing.T) {
+	const budget = 10
+	b := newRetryBudget(budget)
+	var taken int64
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if b.take() {
+				atomic.AddInt64(&taken, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if taken != budget || b.spent() != budget {
+		t.Fatalf("took %d retries (spent %d) from a budget of %d", taken, b.spent(), budget)
+	}
+	if b.take() {
+		t.Fatal("exhausted budget allowed another retry")
+	}
+}
+
+func TestRetryBudgetStopsRetries(t *testing.T) {
+	const workers, budget = 3, 5
+	result, err := RunSimulation(workers, 5*time.Second,
+		WithRetryBudget(budget),
+		WithFaultInjection(&FaultInjector{FailureRate: 1}), // Every attempt fails
+	)
+	if !errors.Is(err, ErrInjectedFault) {
+		t.Fatalf("got %v, want the injected faults reported", err)
+	}
+	if result.Retries != budget {
+		t.Fatalf("spent %d retries, want the whole budget of %d and no more", result.Retries, budget)
+	}
+	if result.Failures != 2*workers {
+		t.Fatalf("got %d failures, want every read and write to fail once the budget ran out", result.Failures)
+	}
+}
+
+func TestNoRetriesWithoutBudget(t *testing.T) {
+	result, _ := RunSimulation(2, 5*time.Second, WithFaultInjection(&FaultInjector{FailureRate: 1}))
+	if result.Retries != 0 || result.Failures != 4 {
+		t.Fatalf("got %d retries and %d failures, want none retried", result.Retries, result.Failures)
+	}
+}
+
+func TestAttemptDeadlineSplitsRemainingTime(t *testing.T) {
+	const total, attempts = 100 * time.Millisecond, 4
+	p := retryPolicy{attempts: attempts, deadline: total}
+
+	var shares []time.Duration
+	start := time.Now()
+	err := p.run(context.Background(), func(ctx context.Context) error {
+		deadline, _ := ctx.Deadline()
+		shares = append(shares, time.Until(deadline))
+		<-ctx.Done() // Every attempt hangs until its share runs out
+		return ctx.Err()
+	})
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want DeadlineExceeded", err)
+	}
+	if len(shares) != attempts {
+		t.Fatalf("made %d attempts, want %d", len(shares), attempts)
+	}
+	for i, share := range shares {
+		if share > total/attempts+5*time.Millisecond {
+			t.Errorf("attempt %d was given %v, want about an equal share of %v", i, share, total/attempts)
+		}
+	}
+	if elapsed > total+50*time.Millisecond {
+		t.Fatalf("retries took %v, want them bounded by the %v budget", elapsed, total)
+	}
+}
+
+func TestAttemptDeadlineLastAttemptGetsRemainder(t *testing.T) {
+	const total = 60 * time.Millisecond
+	p := retryPolicy{attempts: 2, deadline: total}
+
+	var last time.Duration
+	attempt := 0
+	p.run(context.Background(), func(ctx context.Context) error {
+		attempt++
+		if attempt == 1 {
+			return errors.New("fails fast") // Leaves almost the whole budget
+		}
+		deadline, _ := ctx.Deadline()
+		last = time.Until(deadline)
+		return nil
+	})
+	if last < total*3/4 {
+		t.Fatalf("last attempt was given %v, want nearly all of the %v remaining", last, total)
+	}
+}
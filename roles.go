@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrForbidden is returned when a worker issues an operation its role does not allow.
+var ErrForbidden = errors.New("operation not allowed for worker role")
+
+// Role limits which operations a worker may issue.
+type Role int
+
+const (
+	// RoleAdmin may read and write. It is the default role.
+	RoleAdmin Role = iota
+	// RoleReader may only read.
+	RoleReader
+	// RoleWriter may only write.
+	RoleWriter
+)
+
+// String returns the name of the role.
+func (r Role) String() string {
+	switch r {
+	case RoleAdmin:
+		return "admin"
+	case RoleReader:
+		return "reader"
+	case RoleWriter:
+		return "writer"
+	default:
+		return fmt.Sprintf("Role(%d)", int(r))
+	}
+}
+
+// authorize returns ErrForbidden if role may not issue op.
+func (r Role) authorize(op string) error {
+	switch {
+	case r == RoleAdmin,
+		r == RoleReader && op == OpRead,
+		r == RoleWriter && op == OpWrite:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s may not %s", ErrForbidden, r, op)
+	}
+}
+
+// WithWorkerRoles assigns roles[i] to worker i. Workers beyond the end of roles are admins.
+// Operations a worker's role forbids fail with ErrForbidden and count as failures.
+func WithWorkerRoles(roles ...Role) SimulationOption {
+	return func(c *simulationConfig) {
+		c.roles = roles
+	}
+}
+
+// role returns the role of worker i.
+func (c *simulationConfig) role(i int) Role {
+	if i < len(c.roles) {
+		return c.roles[i]
+	}
+	return RoleAdmin
+}
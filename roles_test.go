@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRoleAuthorize(t *testing.T) {
+	tests := []struct {
+		role    Role
+		op      string
+		allowed bool
+	}{
+		{RoleAdmin, OpRead, true},
+		{RoleAdmin, OpWrite, true},
+		{RoleReader, OpRead, true},
+		{RoleReader, OpWrite, false},
+		{RoleWriter, OpRead, false},
+		{RoleWriter, OpWrite, true},
+	}
+	for _, tt := range tests {
+		err := tt.role.authorize(tt.op)
+		if allowed := err == nil; allowed != tt.allowed {
+			t.Errorf("%s %s: got %v", tt.role, tt.op, err)
+		}
+		if err != nil && !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s %s: got %v, want ErrForbidden", tt.role, tt.op, err)
+		}
+	}
+}
+
+func TestWorkerRolesInSimulation(t *testing.T) {
+	result, err := RunSimulation(3, 5*time.Second, WithWorkerRoles(RoleReader, RoleAdmin, RoleWriter))
+	if !errors.Is(err, ErrForbidden) {
+		t.Fatalf("got %v, want the forbidden operations reported", err)
+	}
+	if result.Failures != 2 || result.Reads != 2 || result.Writes != 2 {
+		t.Fatalf("got %d reads, %d writes and %d failures, want the reader's write and the writer's read rejected",
+			result.Reads, result.Writes, result.Failures)
+	}
+	for _, id := range result.WriteOrder {
+		if id == 1 {
+			t.Fatal("reader worker managed to write")
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// WithMetricsSampling records the latency of only a fraction rate of operations in the metrics
+// histograms, reducing overhead at high throughput. Operation counts stay exact. Sampling decisions
+// are drawn from rnd, so a seeded source makes them deterministic; a nil rnd uses the global source.
+func WithMetricsSampling(rate float64, rnd *rand.Rand) ResourceOption {
+	return func(r *Resource) {
+		r.metrics.sampler = &latencySampler{rate: rate, rnd: rnd}
+	}
+}
+
+// latencySampler decides which operations contribute latency samples.
+type latencySampler struct {
+	rate float64
+
+	mu  sync.Mutex // Guards rnd, which is not safe for concurrent use
+	rnd *rand.Rand
+}
+
+// sample reports whether the next operation should be timed. A nil sampler times every operation.
+func (s *latencySampler) sample() bool {
+	if s == nil || s.rate >= 1 {
+		return true
+	}
+	if s.rate <= 0 {
+		return false
+	}
+	if s.rnd == nil {
+		return rand.Float64() < s.rate
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rnd.Float64() < s.rate
+}
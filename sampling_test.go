@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+)
+
+// sampledReads returns how many reads contributed a latency sample to r's metrics.
+func sampledReads(r *Resource) uint64 {
+	var n uint64
+	for _, b := range r.Metrics().ReadLatency.Buckets {
+		n += b.Count
+	}
+	return n
+}
+
+func TestMetricsSampling(t *testing.T) {
+	const reads = 2000
+	r := NewResource("data", WithMetricsSampling(0.1, rand.New(rand.NewSource(1))))
+	for i := 0; i < reads; i++ {
+		if _, err := r.Read(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if m := r.Metrics(); m.Reads != reads {
+		t.Fatalf("counted %d reads, want all %d", m.Reads, reads)
+	}
+	if n := sampledReads(r); n < reads/20 || n > reads*3/20 {
+		t.Fatalf("sampled %d of %d reads, want roughly a tenth", n, reads)
+	}
+}
+
+func TestCloneKeepsMetricsSampling(t *testing.T) {
+	const reads = 2000
+	c := NewResource("data", WithMetricsSampling(0.1, rand.New(rand.NewSource(1)))).Clone()
+	for i := 0; i < reads; i++ {
+		c.Read(context.Background())
+	}
+	if n := sampledReads(c); n > reads*3/20 {
+		t.Fatalf("clone sampled %d of %d reads, want roughly a tenth", n, reads)
+	}
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrSimulationDeadline is returned by RunSimulation when the run exceeds its maximum duration.
+var ErrSimulationDeadline = errors.New("simulation exceeded its maximum duration")
+
+// SimulationResult summarizes the outcome of a simulation run.
+type SimulationResult struct {
+	FinalValue string          // Final state of the first resource
+	Reads      int             // Successful read operations
+	Writes     int             // Successful write operations
+	Failures   int             // Operations that returned an error
+	Retries    int             // Retries spent from the budget set by WithRetryBudget
+	WriteOrder []int           // IDs of workers in the order their writes succeeded
+	Events     []Event         // Timeline of operations performed during the run
+	Resources  []ResourceStats // Statistics for each resource, in creation order
+
+	Duration        time.Duration // Wall-clock time of the counted part of the run
+	Throughput      float64       // Successful operations per second
+	ReadThroughput  float64       // Successful reads per second
+	WriteThroughput float64       // Successful writes per second
+
+	ReadLatency Histogram // Latency of reads across all resources
+	Finished    time.Time // When FinalValue was read; zero if the run did not finish
+}
+
+// ResourceStats summarizes the operations performed against a single resource.
+type ResourceStats struct {
+	FinalValue string // Final state of the resource
+	Reads      int    // Successful read operations
+	Writes     int    // Successful write operations
+	Failures   int    // Operations that returned an error
+	WriteOrder []int  // IDs of workers in the order their writes to this resource succeeded
+}
+
+// newSimulationResult creates an empty result for a run over numResources resources.
+func newSimulationResult(numResources int) SimulationResult {
+	return SimulationResult{Resources: make([]ResourceStats, numResources)}
+}
+
+// recordRead records the outcome of a read operation on the given resource.
+func (s *SimulationResult) recordRead(resource int, err error) {
+	stats := &s.Resources[resource]
+	if err != nil {
+		s.Failures++
+		stats.Failures++
+		return
+	}
+	s.Reads++
+	stats.Reads++
+}
+
+// recordWrite records the outcome of a write operation by the given worker on the given resource.
+func (s *SimulationResult) recordWrite(resource, workerID int, err error) {
+	stats := &s.Resources[resource]
+	if err != nil {
+		s.Failures++
+		stats.Failures++
+		return
+	}
+	s.Writes++
+	s.WriteOrder = append(s.WriteOrder, workerID)
+	stats.Writes++
+	stats.WriteOrder = append(stats.WriteOrder, workerID)
+}
+
+// setDuration records the counted duration of the run and derives throughput from the counts.
+func (s *SimulationResult) setDuration(d time.Duration) {
+	s.Duration = d
+	if d <= 0 {
+		return
+	}
+	seconds := d.Seconds()
+	s.Throughput = float64(s.Reads+s.Writes) / seconds
+	s.ReadThroughput = float64(s.Reads) / seconds
+	s.WriteThroughput = float64(s.Writes) / seconds
+}
+
+// FormatThroughput returns the run's throughput as a one-line summary.
+func (s SimulationResult) FormatThroughput() string {
+	return fmt.Sprintf("%.2f ops/s (reads %.2f/s, writes %.2f/s) over %v",
+		s.Throughput, s.ReadThroughput, s.WriteThroughput, s.Duration)
+}
+
+// clone returns a deep copy of the result that shares no slices with the original.
+func (s SimulationResult) clone() SimulationResult {
+	c := s
+	c.WriteOrder = append([]int(nil), s.WriteOrder...)
+	c.Events = append([]Event(nil), s.Events...)
+	c.ReadLatency.Buckets = append([]HistogramBucket(nil), s.ReadLatency.Buckets...)
+	c.Resources = make([]ResourceStats, len(s.Resources))
+	for i, stats := range s.Resources {
+		stats.WriteOrder = append([]int(nil), stats.WriteOrder...)
+		c.Resources[i] = stats
+	}
+	return c
+}
+
+// Merge combines the statistics of two runs, such as the shards or phases of one workload.
+// Counts and latency histograms are summed, event timelines are merged by start time, and the final
+// values come from whichever run finished later. Durations are added, as for consecutive phases,
+// and throughput is recomputed from the combined counts. Per-resource statistics are merged by index.
+func (s SimulationResult) Merge(other SimulationResult) SimulationResult {
+	first, last := s, other
+	if other.Finished.Before(s.Finished) {
+		first, last = other, s
+	}
+
+	m := SimulationResult{
+		FinalValue:  last.FinalValue,
+		Reads:       s.Reads + other.Reads,
+		Writes:      s.Writes + other.Writes,
+		Failures:    s.Failures + other.Failures,
+		Retries:     s.Retries + other.Retries,
+		WriteOrder:  append(append([]int(nil), first.WriteOrder...), last.WriteOrder...),
+		ReadLatency: s.ReadLatency.Merge(other.ReadLatency),
+		Finished:    last.Finished,
+	}
+	m.Events = append(append([]Event(nil), s.Events...), other.Events...)
+	sort.SliceStable(m.Events, func(i, j int) bool {
+		return m.Events[i].Start.Before(m.Events[j].Start)
+	})
+
+	m.Resources = make([]ResourceStats, max(len(s.Resources), len(other.Resources)))
+	for i := range m.Resources {
+		var a, b ResourceStats
+		if i < len(first.Resources) {
+			a = first.Resources[i]
+		}
+		if i < len(last.Resources) {
+			b = last.Resources[i]
+		}
+		m.Resources[i] = ResourceStats{
+			FinalValue: a.FinalValue,
+			Reads:      a.Reads + b.Reads,
+			Writes:     a.Writes + b.Writes,
+			Failures:   a.Failures + b.Failures,
+			WriteOrder: append(append([]int(nil), a.WriteOrder...), b.WriteOrder...),
+		}
+		if i < len(last.Resources) {
+			m.Resources[i].FinalValue = b.FinalValue
+		}
+	}
+	m.setDuration(s.Duration + other.Duration)
+	return m
+}
+
+// SimulationOption configures optional behavior of RunSimulation.
+type SimulationOption func(*simulationConfig)
+
+// simulationConfig holds the settings applied by SimulationOptions.
+type simulationConfig struct {
+	resourceFactory func(data string) *Resource
+	maxDuration     time.Duration // Wall-clock cap for the whole run; zero means no cap
+	failFast        bool          // Cancel the run on the first operation error
+	numResources    int           // Number of independent resources workers are spread across
+	maxConcurrency  int           // Operations allowed to run at once across all workers; zero means unlimited
+	warmup          time.Duration // Initial phase whose operations are not counted
+	measure         time.Duration // Phase after warmup during which workers keep cycling and are counted
+	arrival         ArrivalSchedule
+	retryBudget     int           // Retries shared by all workers; zero means no shared allowance
+	roles           []Role        // Role of each worker by index
+	attemptDeadline time.Duration // Time allowed per operation across its retries; zero means unbounded
+	maxAttempts     int           // Attempts allowed per operation; zero means limited only by the retry budget
+	faults          *FaultInjector
+	minRemaining    time.Duration // Least time left for a worker to start an operation
+}
+
+// newSimulationConfig applies opts on top of the default configuration.
+func newSimulationConfig(opts []SimulationOption) *simulationConfig {
+	cfg := &simulationConfig{numResources: 1}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// newResource creates the shared resource using the configured factory, if any.
+// The resource records its event timeline, which becomes SimulationResult.Events.
+func (c *simulationConfig) newResource(data string) *Resource {
+	if c.resourceFactory == nil {
+		return NewResource(data, WithEventTimeline())
+	}
+	r := c.resourceFactory(data)
+	r.recordTimeline()
+	return r
+}
+
+// WithResourceFactory makes the simulation create its resource with factory instead of NewResource.
+// This allows the same workload to be run against differently configured resources.
+// The simulation enables the event timeline on every resource factory returns.
+func WithResourceFactory(factory func(data string) *Resource) SimulationOption {
+	return func(c *simulationConfig) {
+		c.resourceFactory = factory
+	}
+}
+
+// WithMaxDuration caps the wall-clock time of the whole run, independent of per-operation timeouts.
+// Once exceeded, every worker is canceled and RunSimulation returns with ErrSimulationDeadline.
+func WithMaxDuration(d time.Duration) SimulationOption {
+	return func(c *simulationConfig) {
+		c.maxDuration = d
+	}
+}
+
+// WithFailFast cancels every worker as soon as any operation fails and returns that error.
+// Without it the simulation runs to completion and returns all operation errors joined together.
+func WithFailFast() SimulationOption {
+	return func(c *simulationConfig) {
+		c.failFast = true
+	}
+}
+
+// WithResources spreads the workers round-robin across n independent resources instead of a single shared one.
+// Per-resource statistics are reported in SimulationResult.Resources.
+func WithResources(n int) SimulationOption {
+	return func(c *simulationConfig) {
+		if n > 0 {
+			c.numResources = n
+		}
+	}
+}
+
+// WithMaxConcurrency bounds how many worker operations run at once, regardless of the number of workers.
+// Operations beyond the limit wait for a slot, giving up if their context is canceled.
+func WithMaxConcurrency(n int) SimulationOption {
+	return func(c *simulationConfig) {
+		c.maxConcurrency = n
+	}
+}
+
+// WithPhases splits the run into a warmup phase, whose operations are excluded from the result,
+// followed by a measurement phase. Workers repeat their read/write cycle until both phases have
+// elapsed, and only operations started during the measurement phase are counted. The timeout
+// given to RunSimulation bounds each cycle, so the phases may together run longer than it.
+func WithPhases(warmup, measure time.Duration) SimulationOption {
+	return func(c *simulationConfig) {
+		c.warmup = warmup
+		c.measure = measure
+	}
+}
+
+// WithMinRemainingTime makes workers skip operations whose context has less than min left
+// before its deadline, failing them with ErrInsufficientTime instead.
+func WithMinRemainingTime(min time.Duration) SimulationOption {
+	return func(c *simulationConfig) {
+		c.minRemaining = min
+	}
+}
+
+// measured reports whether an operation started at elapsed time into the run counts towards the result.
+func (c *simulationConfig) measured(elapsed time.Duration) bool {
+	if elapsed < c.warmup {
+		return false
+	}
+	return c.measure <= 0 || elapsed < c.warmup+c.measure
+}
+
+// countedDuration returns how much of a run lasting elapsed fell within the counted phase.
+func (c *simulationConfig) countedDuration(elapsed time.Duration) time.Duration {
+	d := elapsed - c.warmup
+	if c.measure > 0 && d > c.measure {
+		d = c.measure
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// repeat reports whether a worker should start another cycle at elapsed time into the run.
+func (c *simulationConfig) repeat(elapsed time.Duration) bool {
+	return c.measure > 0 && elapsed < c.warmup+c.measure
+}
+
+// ArrivalSchedule returns how long after the start of the run worker i of n begins working.
+type ArrivalSchedule func(i, n int) time.Duration
+
+// RampArrival spreads worker start times evenly across over, so load builds up gradually.
+func RampArrival(over time.Duration) ArrivalSchedule {
+	return func(i, n int) time.Duration {
+		if n <= 1 {
+			return 0
+		}
+		return over * time.Duration(i) / time.Duration(n-1)
+	}
+}
+
+// RateArrival starts perSecond new workers every second.
+func RateArrival(perSecond float64) ArrivalSchedule {
+	return func(i, n int) time.Duration {
+		return time.Duration(float64(i) / perSecond * float64(time.Second))
+	}
+}
+
+// WithArrivalSchedule staggers worker start times according to schedule instead of starting all at once.
+func WithArrivalSchedule(schedule ArrivalSchedule) SimulationOption {
+	return func(c *simulationConfig) {
+		c.arrival = schedule
+	}
+}
+
+// arrivalDelay returns how long worker i of n waits before starting.
+func (c *simulationConfig) arrivalDelay(i, n int) time.Duration {
+	if c.arrival == nil {
+		return 0
+	}
+	return c.arrival(i, n)
+}
+
+// semaphore bounds concurrent operations. A nil semaphore imposes no limit.
+type semaphore chan struct{}
+
+// newSemaphore creates a semaphore with n slots, or a nil semaphore if n is not positive.
+func newSemaphore(n int) semaphore {
+	if n <= 0 {
+		return nil
+	}
+	return make(semaphore, n)
+}
+
+// run waits for a free slot, runs op, and releases the slot. It returns the context error if ctx is done first.
+func (s semaphore) run(ctx context.Context, op func() error) error {
+	if s == nil {
+		return op()
+	}
+	select {
+	case s <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-s }()
+	return op()
+}
+
+// Diff lists the differences found between two simulation results.
+type Diff struct {
+	Differences []string
+}
+
+// Empty reports whether the compared results were identical.
+func (d Diff) Empty() bool {
+	return len(d.Differences) == 0
+}
+
+// String returns one difference per line.
+func (d Diff) String() string {
+	return strings.Join(d.Differences, "\n")
+}
+
+// CompareRuns compares two simulation results and reports differences in final value, counts, or write ordering.
+func CompareRuns(a, b SimulationResult) Diff {
+	var d Diff
+	if a.FinalValue != b.FinalValue {
+		d.Differences = append(d.Differences, fmt.Sprintf("final value: %q != %q", a.FinalValue, b.FinalValue))
+	}
+	if a.Reads != b.Reads {
+		d.Differences = append(d.Differences, fmt.Sprintf("reads: %d != %d", a.Reads, b.Reads))
+	}
+	if a.Writes != b.Writes {
+		d.Differences = append(d.Differences, fmt.Sprintf("writes: %d != %d", a.Writes, b.Writes))
+	}
+	if a.Failures != b.Failures {
+		d.Differences = append(d.Differences, fmt.Sprintf("failures: %d != %d", a.Failures, b.Failures))
+	}
+	for i := 0; i < len(a.WriteOrder) || i < len(b.WriteOrder); i++ {
+		if i >= len(a.WriteOrder) || i >= len(b.WriteOrder) || a.WriteOrder[i] != b.WriteOrder[i] {
+			d.Differences = append(d.Differences, fmt.Sprintf("write order diverges at position %d: %v != %v", i, a.WriteOrder, b.WriteOrder))
+			break
+		}
+	}
+	if len(a.Resources) != len(b.Resources) {
+		d.Differences = append(d.Differences, fmt.Sprintf("resources: %d != %d", len(a.Resources), len(b.Resources)))
+	}
+	return d
+}
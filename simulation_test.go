@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPhasesExcludeWarmup(t *testing.T) {
+	const workers = 2
+	result, err := RunSimulation(workers, 5*time.Second, WithPhases(1500*time.Millisecond, 1500*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each cycle takes about a second, so the first read of each worker falls in the warmup
+	total := 0
+	for _, ev := range result.Events {
+		if ev.Err == nil {
+			total++
+		}
+	}
+	if counted := result.Reads + result.Writes; counted >= total || counted == 0 {
+		t.Fatalf("counted %d of %d operations, want the warmup ones excluded", counted, total)
+	}
+	if result.Reads < workers {
+		t.Fatalf("got %d measured reads, want at least one per worker", result.Reads)
+	}
+}
+
+func TestPhasesLongerThanTimeout(t *testing.T) {
+	const workers = 2
+	result, err := RunSimulation(workers, 1500*time.Millisecond, WithPhases(0, 2500*time.Millisecond))
+	if err != nil {
+		t.Fatalf("cycles after the timeout failed: %v", err)
+	}
+	if result.Failures != 0 {
+		t.Fatalf("got %d failures", result.Failures)
+	}
+	if result.Writes < 2*workers {
+		t.Fatalf("got %d writes, want every worker to keep cycling past the timeout", result.Writes)
+	}
+}
+
+func TestCompareRuns(t *testing.T) {
+	a := SimulationResult{FinalValue: "x", Reads: 3, Writes: 2, WriteOrder: []int{1, 2}, Resources: make([]ResourceStats, 1)}
+	if d := CompareRuns(a, a.clone()); !d.Empty() {
+		t.Fatalf("identical runs differ: %s", d)
+	}
+
+	b := a.clone()
+	b.FinalValue = "y"
+	b.Failures = 1
+	b.WriteOrder = []int{2, 1}
+	d := CompareRuns(a, b)
+	want := []string{"final value", "failures", "write order diverges at position 0"}
+	if len(d.Differences) != len(want) {
+		t.Fatalf("got differences %q, want %d", d.Differences, len(want))
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(d.Differences[i], prefix) {
+			t.Errorf("difference %d: got %q, want it to start with %q", i, d.Differences[i], prefix)
+		}
+	}
+}
+
+func TestMaxDurationCapsRetryingRun(t *testing.T) {
+	const limit = 200 * time.Millisecond
+	start := time.Now()
+	_, err := RunSimulation(3, 10*time.Second,
+		WithMaxDuration(limit),
+		WithRetryBudget(1<<30),
+		WithFaultInjection(&FaultInjector{FailureRate: 1}), // Every attempt fails, so workers retry endlessly
+	)
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, ErrSimulationDeadline) {
+		t.Fatalf("got %v, want ErrSimulationDeadline", err)
+	}
+	if elapsed > limit+500*time.Millisecond {
+		t.Fatalf("returned after %v, want close to the %v cap", elapsed, limit)
+	}
+}
+
+func TestFailFastCancelsRun(t *testing.T) {
+	start := time.Now()
+	result, err := RunSimulation(2, 5*time.Second,
+		WithWorkerRoles(RoleReader), // Worker 1's writes are forbidden
+		WithPhases(0, 1500*time.Millisecond),
+		WithFailFast(),
+	)
+	if !errors.Is(err, ErrForbidden) {
+		t.Fatalf("got %v, want the first error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 1500*time.Millisecond {
+		t.Fatalf("run took %v, want it cancelled at the first failed write", elapsed)
+	}
+	if result.Failures == 0 {
+		t.Fatal("failure not recorded")
+	}
+}
+
+func TestWithoutFailFastCollectsAllErrors(t *testing.T) {
+	result, err := RunSimulation(2, 5*time.Second,
+		WithWorkerRoles(RoleReader),
+		WithPhases(0, 2500*time.Millisecond), // Two measured writes per worker
+	)
+	if !errors.Is(err, ErrForbidden) {
+		t.Fatalf("got %v, want the joined errors", err)
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok || len(joined.Unwrap()) != 2 {
+		t.Fatalf("got %v, want both forbidden writes reported", err)
+	}
+	if result.Failures != 2 || result.Writes != 2 {
+		t.Fatalf("got %d failures and %d writes, want the other worker to keep writing", result.Failures, result.Writes)
+	}
+}
+
+func TestMultipleResourcesKeepStatsPerResource(t *testing.T) {
+	const workers, numResources = 4, 2
+	result, err := RunSimulation(workers, 5*time.Second, WithResources(numResources))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Resources) != numResources {
+		t.Fatalf("got stats for %d resources, want %d", len(result.Resources), numResources)
+	}
+
+	// Workers are assigned round-robin, so worker IDs 1 and 3 share resource 0 and 2 and 4 share resource 1
+	total := 0
+	for i, stats := range result.Resources {
+		if stats.Reads != workers/numResources || stats.Writes != workers/numResources {
+			t.Errorf("resource %d: got %d reads and %d writes", i, stats.Reads, stats.Writes)
+		}
+		for _, id := range stats.WriteOrder {
+			if (id-1)%numResources != i {
+				t.Errorf("resource %d received a write from worker %d", i, id)
+			}
+		}
+		last := stats.WriteOrder[len(stats.WriteOrder)-1]
+		if want := fmt.Sprintf("new data written by Worker %d", last); stats.FinalValue != want {
+			t.Errorf("resource %d: final value %q, want %q", i, stats.FinalValue, want)
+		}
+		total += stats.Writes
+	}
+	if total != result.Writes {
+		t.Fatalf("per-resource writes sum to %d, want the overall %d", total, result.Writes)
+	}
+}
+
+func TestMaxConcurrencyBoundsOperations(t *testing.T) {
+	const workers, limit = 6, 2
+	var active, peak int32
+	slowRead := Transformer{
+		OnRead: func(data string) (string, error) {
+			n := atomic.AddInt32(&active, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond) // Hold the operation open so others can pile up
+			atomic.AddInt32(&active, -1)
+			return data, nil
+		},
+	}
+
+	_, err := RunSimulation(workers, 5*time.Second,
+		WithMaxConcurrency(limit),
+		WithResourceFactory(func(data string) *Resource {
+			return NewResource(data, WithTransformers(slowRead))
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := atomic.LoadInt32(&peak); p > limit || p == 0 {
+		t.Fatalf("up to %d operations ran at once, want at most %d", p, limit)
+	}
+}
+
+func TestSemaphoreGivesUpOnCancel(t *testing.T) {
+	s := newSemaphore(1)
+	hold := make(chan struct{})
+	go s.run(context.Background(), func() error { <-hold; return nil })
+	for len(s) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	defer close(hold)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ran := false
+	if err := s.run(ctx, func() error { ran = true; return nil }); !errors.Is(err, context.DeadlineExceeded) || ran {
+		t.Fatalf("got %v with ran %v, want the waiting operation abandoned", err, ran)
+	}
+}
+
+func TestArrivalSchedules(t *testing.T) {
+	ramp := RampArrival(time.Second)
+	for i, want := range []time.Duration{0, 500 * time.Millisecond, time.Second} {
+		if got := ramp(i, 3); got != want {
+			t.Errorf("ramp worker %d: got %v, want %v", i, got, want)
+		}
+	}
+	if got := ramp(0, 1); got != 0 {
+		t.Errorf("single worker ramp: got %v, want 0", got)
+	}
+	rate := RateArrival(4)
+	if got := rate(2, 10); got != 500*time.Millisecond {
+		t.Errorf("rate worker 2: got %v, want 500ms", got)
+	}
+}
+
+func TestRampArrivalStaggersWorkers(t *testing.T) {
+	const workers, over = 3, 600 * time.Millisecond
+	result, err := RunSimulation(workers, 5*time.Second, WithArrivalSchedule(RampArrival(over)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first operation of each worker marks when it arrived
+	first := make(map[int]time.Time)
+	var runStart time.Time
+	for _, ev := range result.Events {
+		if _, ok := first[ev.Worker.ID]; !ok {
+			first[ev.Worker.ID] = ev.Start
+		}
+		if runStart.IsZero() || ev.Start.Before(runStart) {
+			runStart = ev.Start
+		}
+	}
+	if len(first) != workers {
+		t.Fatalf("got operations from %d workers, want %d", len(first), workers)
+	}
+	for id := 1; id <= workers; id++ {
+		want := over * time.Duration(id-1) / (workers - 1)
+		if got := first[id].Sub(runStart); got < want-50*time.Millisecond || got > want+200*time.Millisecond {
+			t.Errorf("worker %d arrived after %v, want about %v", id, got, want)
+		}
+	}
+}
+
+func TestThroughputFromCounts(t *testing.T) {
+	s := SimulationResult{Reads: 30, Writes: 10}
+	s.setDuration(2 * time.Second)
+	if s.Throughput != 20 || s.ReadThroughput != 15 || s.WriteThroughput != 5 {
+		t.Fatalf("got %v, %v and %v ops/s", s.Throughput, s.ReadThroughput, s.WriteThroughput)
+	}
+	if got, want := s.FormatThroughput(), "20.00 ops/s (reads 15.00/s, writes 5.00/s) over 2s"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	var empty SimulationResult
+	empty.setDuration(0)
+	if empty.Throughput != 0 {
+		t.Fatalf("zero-length run has throughput %v", empty.Throughput)
+	}
+}
+
+func TestSimulationThroughput(t *testing.T) {
+	// Each worker reads, waits about a second, and writes once, so four workers do 8 operations in about 1s
+	const workers = 4
+	result, err := RunSimulation(workers, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Reads+result.Writes != 2*workers {
+		t.Fatalf("got %d operations, want %d", result.Reads+result.Writes, 2*workers)
+	}
+	if result.Duration < time.Second || result.Duration > 2*time.Second {
+		t.Fatalf("run took %v, want about a second", result.Duration)
+	}
+	if result.Throughput < 4 || result.Throughput > 8 {
+		t.Fatalf("throughput %.2f ops/s, want between 4 and 8", result.Throughput)
+	}
+	if result.ReadThroughput != result.WriteThroughput {
+		t.Fatalf("read throughput %v differs from write throughput %v with one of each per worker",
+			result.ReadThroughput, result.WriteThroughput)
+	}
+}
+
+func TestMinRemainingTimeSkipsDoomedOperations(t *testing.T) {
+	start := time.Now()
+	result, err := RunSimulation(2, 500*time.Millisecond, WithMinRemainingTime(time.Second))
+	if err == nil {
+		t.Fatal("run with every operation skipped reported no error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("run took %v, want workers to skip instead of waiting out their operations", elapsed)
+	}
+	if result.Writes != 0 || result.Failures == 0 {
+		t.Fatalf("got %d writes and %d failures, want every operation skipped", result.Writes, result.Failures)
+	}
+	if len(result.Events) != 0 {
+		t.Fatalf("got %d events, want skipped operations never to reach the resource", len(result.Events))
+	}
+}
+
+func TestMergeResults(t *testing.T) {
+	now := time.Now()
+	later := SimulationResult{
+		FinalValue: "later", Reads: 2, Writes: 1, Failures: 1, WriteOrder: []int{3},
+		Duration: time.Second, Finished: now.Add(time.Second),
+		Events:    []Event{{Op: OpWrite, Start: now.Add(time.Second)}},
+		Resources: []ResourceStats{{FinalValue: "later", Reads: 2, Writes: 1, Failures: 1, WriteOrder: []int{3}}},
+	}
+	earlier := SimulationResult{
+		FinalValue: "earlier", Reads: 3, Writes: 2, WriteOrder: []int{1, 2},
+		Duration: time.Second, Finished: now,
+		Events:    []Event{{Op: OpRead, Start: now}},
+		Resources: []ResourceStats{{FinalValue: "earlier", Reads: 3, Writes: 2, WriteOrder: []int{1, 2}}, {FinalValue: "only", Writes: 1}},
+	}
+
+	// The order of the arguments does not matter
+	for _, m := range []SimulationResult{later.Merge(earlier), earlier.Merge(later)} {
+		if m.Reads != 5 || m.Writes != 3 || m.Failures != 1 {
+			t.Fatalf("got %d reads, %d writes and %d failures, want 5, 3 and 1", m.Reads, m.Writes, m.Failures)
+		}
+		if m.FinalValue != "later" || !m.Finished.Equal(later.Finished) {
+			t.Fatalf("final value %q, want the one from the run that finished later", m.FinalValue)
+		}
+		if fmt.Sprint(m.WriteOrder) != "[1 2 3]" {
+			t.Fatalf("write order %v, want the earlier run's writes first", m.WriteOrder)
+		}
+		if len(m.Events) != 2 || m.Events[0].Op != OpRead {
+			t.Fatalf("events %+v, want them ordered by start time", m.Events)
+		}
+		if m.Duration != 2*time.Second || m.Throughput != 4 {
+			t.Fatalf("duration %v and throughput %v, want 2s and 4 operations per second", m.Duration, m.Throughput)
+		}
+		if len(m.Resources) != 2 || m.Resources[0].FinalValue != "later" || m.Resources[0].Reads != 5 || m.Resources[1].FinalValue != "only" {
+			t.Fatalf("per-resource stats %+v", m.Resources)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// WithSlowOperationLog logs every read or write that takes longer than threshold,
+// with its lock-wait and hold times, while faster operations stay quiet.
+func WithSlowOperationLog(threshold time.Duration) ResourceOption {
+	return func(r *Resource) {
+		r.slowThreshold = int64(threshold)
+	}
+}
+
+// SetSlowOperationThreshold changes the slow-operation threshold at runtime.
+// A non-positive threshold disables the slow-operation log.
+func (r *Resource) SetSlowOperationThreshold(threshold time.Duration) {
+	atomic.StoreInt64(&r.slowThreshold, int64(threshold))
+}
+
+// SlowOperationThreshold returns the current slow-operation threshold, or zero if disabled.
+func (r *Resource) SlowOperationThreshold() time.Duration {
+	return time.Duration(atomic.LoadInt64(&r.slowThreshold))
+}
+
+// logSlow logs ev if it took longer than the slow-operation threshold.
+func (r *Resource) logSlow(ev Event) {
+	threshold := r.SlowOperationThreshold()
+	if threshold <= 0 || ev.Duration <= threshold {
+		return
+	}
+	fmt.Printf("Slow %s by %s: took %v (lock wait %v, hold %v, threshold %v)\n",
+		ev.Op, ev.Worker, ev.Duration, ev.LockWait, ev.Duration-ev.LockWait, threshold)
+}
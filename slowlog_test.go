@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowOperationLogOnlyLogsSlow(t *testing.T) {
+	const threshold = 10 * time.Millisecond
+	r := NewResource("initial", WithSlowOperationLog(threshold))
+	ctx := context.Background()
+
+	output := captureOutput(t, func() {
+		r.Read(ctx)
+		r.WithReadLock(ctx, func(string) error {
+			time.Sleep(2 * threshold)
+			return nil
+		})
+	})
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], "Slow read") {
+		t.Fatalf("got log %q, want exactly one slow read logged", output)
+	}
+	if !strings.Contains(lines[0], "lock wait") || !strings.Contains(lines[0], "hold") {
+		t.Fatalf("slow log line %q lacks the lock-wait and hold times", lines[0])
+	}
+}
+
+func TestSetSlowOperationThreshold(t *testing.T) {
+	r := NewResource("initial")
+	slowRead := func() {
+		r.WithReadLock(context.Background(), func(string) error {
+			time.Sleep(5 * time.Millisecond)
+			return nil
+		})
+	}
+	if output := captureOutput(t, slowRead); output != "" {
+		t.Fatalf("logged %q with the log disabled", output)
+	}
+
+	r.SetSlowOperationThreshold(time.Millisecond)
+	if r.SlowOperationThreshold() != time.Millisecond {
+		t.Fatalf("threshold %v, want 1ms", r.SlowOperationThreshold())
+	}
+	if output := captureOutput(t, slowRead); !strings.HasPrefix(output, "Slow read") {
+		t.Fatalf("got %q after enabling the log at runtime", output)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Snapshot is a consistent copy of a resource's state.
+type Snapshot struct {
+	Data    string    // Data at the time of the snapshot, as Read would return it
+	Version uint64    // Number of writes applied before the snapshot
+	Taken   time.Time // Time the snapshot was taken
+}
+
+// BeginSnapshot takes a snapshot and keeps writes blocked until release is called, so a slow
+// export can run against the resource without concurrent mutation. Reads continue, except that
+// new readers queue behind any writer waiting for the snapshot to be released.
+// release must be called exactly once; further calls do nothing.
+func (r *Resource) BeginSnapshot(ctx context.Context) (Snapshot, func(), error) {
+	return r.beginSnapshot(ctx, false)
+}
+
+// BeginExclusiveSnapshot is like BeginSnapshot but blocks reads as well as writes until release is called.
+func (r *Resource) BeginExclusiveSnapshot(ctx context.Context) (Snapshot, func(), error) {
+	return r.beginSnapshot(ctx, true)
+}
+
+// beginSnapshot holds the read lock, or the write lock if exclusive, and snapshots the state under it.
+func (r *Resource) beginSnapshot(ctx context.Context, exclusive bool) (Snapshot, func(), error) {
+	if err := r.checkOpen(); err != nil {
+		return Snapshot{}, nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return Snapshot{}, nil, ctx.Err() // Return error if context is canceled
+	default:
+		lock, unlock := r.mu.RLock, r.mu.RUnlock
+		if exclusive {
+			lock, unlock = r.mu.Lock, r.mu.Unlock
+		}
+		if err := lock(ctx); err != nil { // Hold the lock until release
+			return Snapshot{}, nil, err
+		}
+
+		data, err := r.transformRead(r.data)
+		if err != nil {
+			unlock()
+			return Snapshot{}, nil, err
+		}
+		r.notifyMu.Lock()
+		snap := Snapshot{Data: data, Version: r.version, Taken: time.Now()}
+		r.notifyMu.Unlock()
+
+		var once sync.Once
+		return snap, func() { once.Do(unlock) }, nil
+	}
+}
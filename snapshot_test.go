@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBeginSnapshotBlocksWrites(t *testing.T) {
+	r := NewResource("initial")
+	ctx := context.Background()
+	r.Write(ctx, "before")
+
+	snap, release, err := r.BeginSnapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Data != "before" || snap.Version != 1 {
+		t.Fatalf("got snapshot %+v", snap)
+	}
+
+	written := make(chan error, 1)
+	go func() { written <- r.Write(ctx, "after") }()
+	select {
+	case err := <-written:
+		t.Fatalf("write finished (%v) while the snapshot was held", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release()
+	release() // Further calls do nothing
+	select {
+	case err := <-written:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("write still blocked after release")
+	}
+	if data, _ := r.Read(ctx); data != "after" {
+		t.Fatalf("got %q after the snapshot was released", data)
+	}
+}
+
+func TestBeginSnapshotAllowsReads(t *testing.T) {
+	r := NewResource("initial")
+	_, release, err := r.BeginSnapshot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if data, err := r.Read(ctx); err != nil || data != "initial" {
+		t.Fatalf("read during a snapshot: got %q (%v)", data, err)
+	}
+}
+
+func TestBeginExclusiveSnapshotBlocksReads(t *testing.T) {
+	r := NewResource("initial")
+	_, release, err := r.BeginExclusiveSnapshot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := r.Read(ctx); err == nil {
+		t.Fatal("read succeeded during an exclusive snapshot")
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// eventStreamBuffer is the number of events buffered for each stream before new ones are dropped.
+const eventStreamBuffer = 256
+
+// streamRecord is the JSON representation of an event in an event stream.
+type streamRecord struct {
+	OpID     uint64    `json:"op_id"`
+	ParentID uint64    `json:"parent_id,omitempty"`
+	Worker   string    `json:"worker"`
+	Op       string    `json:"op"`
+	Value    string    `json:"value,omitempty"`
+	Start    time.Time `json:"start"`
+	Duration int64     `json:"duration_ns"`
+	LockWait int64     `json:"lock_wait_ns"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// StreamEvents writes every subsequently completed operation to w as newline-delimited JSON.
+// Events are buffered and written by a separate goroutine, so a slow w never blocks operations;
+// if the buffer fills, new events are dropped and counted in Metrics.DroppedStreamEvents.
+// stop ends the stream and returns once the buffered events have been written.
+func (r *Resource) StreamEvents(w io.Writer) (stop func()) {
+	ch := make(chan Event, eventStreamBuffer)
+	r.streamsMu.Lock()
+	if r.streams == nil {
+		r.streams = make(map[chan Event]struct{})
+	}
+	r.streams[ch] = struct{}{}
+	r.streamsMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		enc := json.NewEncoder(w) // Encode terminates each record with a newline
+		for ev := range ch {
+			rec := streamRecord{
+				OpID:     ev.OpID,
+				ParentID: ev.ParentID,
+				Worker:   ev.Worker.String(),
+				Op:       ev.Op,
+				Value:    ev.Value,
+				Start:    ev.Start,
+				Duration: int64(ev.Duration),
+				LockWait: int64(ev.LockWait),
+			}
+			if ev.Err != nil {
+				rec.Error = ev.Err.Error()
+			}
+			enc.Encode(rec) // A failing writer only loses its own stream
+		}
+	}()
+
+	stopped := false
+	return func() {
+		r.streamsMu.Lock()
+		if !stopped {
+			stopped = true
+			delete(r.streams, ch)
+			close(ch)
+		}
+		r.streamsMu.Unlock()
+		<-done
+	}
+}
+
+// streamEvent hands ev to every event stream without blocking.
+func (r *Resource) streamEvent(ev Event) {
+	r.streamsMu.Lock()
+	defer r.streamsMu.Unlock()
+	for ch := range r.streams {
+		select {
+		case ch <- ev:
+		default:
+			r.metrics.droppedStreamEvents.Add(1) // The stream's writer is behind
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestStreamEventsWritesJSONLines(t *testing.T) {
+	r := NewResource("initial")
+	ctx := WithIdentity(context.Background(), WorkerIdentity{ID: 1})
+	var buf bytes.Buffer
+	stop := r.StreamEvents(&buf)
+
+	r.Read(ctx)
+	r.Write(ctx, "written")
+	r.SetReadOnly(true)
+	r.Write(ctx, "rejected")
+	stop()
+	r.Read(ctx) // After stop, so not streamed
+
+	want := []streamRecord{
+		{Op: OpRead, Value: "initial"},
+		{Op: OpWrite, Value: "written"},
+		{Op: OpWrite, Error: ErrReadOnly.Error()},
+	}
+	scanner := bufio.NewScanner(&buf)
+	var got []streamRecord
+	for scanner.Scan() {
+		var rec streamRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("line %q does not parse: %v", scanner.Text(), err)
+		}
+		got = append(got, rec)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("streamed %d events, want %d:\n%s", len(got), len(want), buf.String())
+	}
+	for i, rec := range got {
+		if rec.Op != want[i].Op || rec.Value != want[i].Value || rec.Error != want[i].Error || rec.Worker != "Worker 1" {
+			t.Errorf("event %d: got %+v", i, rec)
+		}
+	}
+}
+
+// blockingWriter is an io.Writer that never returns until unblocked.
+type blockingWriter struct{ unblock chan struct{} }
+
+func (w blockingWriter) Write(p []byte) (int, error) {
+	<-w.unblock
+	return len(p), nil
+}
+
+func TestStreamEventsSlowWriterDoesNotBlock(t *testing.T) {
+	r := NewResource("initial")
+	w := blockingWriter{unblock: make(chan struct{})}
+	stop := r.StreamEvents(w)
+
+	const reads = eventStreamBuffer + 50
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < reads; i++ {
+			r.Read(context.Background())
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("operations blocked behind a stalled stream writer")
+	}
+	if dropped := r.Metrics().DroppedStreamEvents; dropped == 0 {
+		t.Fatal("no events counted as dropped for the stalled stream")
+	}
+	close(w.unblock)
+	stop()
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrSubscriberOverflow is reported by a subscription closed because its buffer overflowed under OverflowError.
+var ErrSubscriberOverflow = errors.New("subscriber buffer overflowed")
+
+// defaultSubscriberBuffer is the buffer size used when SubscribeOptions.Buffer is not positive.
+const defaultSubscriberBuffer = 16
+
+// OverflowPolicy decides what happens when a write is published to a subscriber whose buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes the writer wait, holding the write lock, until the subscriber catches up,
+	// unsubscribes, or the resource is closed.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest buffered update to make room for the new one.
+	OverflowDropOldest
+	// OverflowDropNewest discards the new update, keeping the buffered ones.
+	OverflowDropNewest
+	// OverflowError closes the subscription and records ErrSubscriberOverflow.
+	OverflowError
+)
+
+// SubscribeOptions configures a subscription.
+type SubscribeOptions struct {
+	Buffer   int            // Number of updates buffered for a slow subscriber
+	Overflow OverflowPolicy // Behavior when the buffer is full
+}
+
+// Subscription delivers the value of every write to a resource.
+type Subscription struct {
+	C <-chan string // Receives the new value after each write; closed on unsubscribe or overflow error
+
+	resource *Resource
+	ch       chan string
+	policy   OverflowPolicy
+	done     chan struct{} // Closed on unsubscribe to release a blocked writer
+	stopOnce sync.Once
+	dropped  uint64 // Updates discarded by the overflow policy
+
+	closed bool  // Whether ch was closed; guarded by resource.subsMu
+	err    error // Why the subscription ended early; guarded by resource.subsMu
+}
+
+// Subscribe registers a subscriber for the value of every subsequent write.
+func (r *Resource) Subscribe(opts SubscribeOptions) *Subscription {
+	buffer := opts.Buffer
+	if buffer <= 0 {
+		buffer = defaultSubscriberBuffer
+	}
+	ch := make(chan string, buffer)
+	s := &Subscription{
+		C:        ch,
+		resource: r,
+		ch:       ch,
+		policy:   opts.Overflow,
+		done:     make(chan struct{}),
+	}
+
+	r.subsMu.Lock()
+	defer r.subsMu.Unlock()
+	if r.subs == nil {
+		r.subs = make(map[*Subscription]struct{})
+	}
+	r.subs[s] = struct{}{}
+	return s
+}
+
+// Unsubscribe stops delivery and closes C. It is safe to call more than once.
+func (s *Subscription) Unsubscribe() {
+	s.stopOnce.Do(func() { close(s.done) }) // Release a writer blocked on this subscriber first
+
+	r := s.resource
+	r.subsMu.Lock()
+	defer r.subsMu.Unlock()
+	r.removeSubscriber(s, nil)
+}
+
+// Dropped returns the number of updates discarded by the subscription's overflow policy.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Err returns ErrSubscriberOverflow if the subscription was closed by its overflow policy, or nil otherwise.
+func (s *Subscription) Err() error {
+	s.resource.subsMu.Lock()
+	defer s.resource.subsMu.Unlock()
+	return s.err
+}
+
+// publish delivers data to every subscriber according to its overflow policy.
+// It is called by every successful write while the write lock is held.
+func (r *Resource) publish(data string) {
+	r.subsMu.Lock()
+	defer r.subsMu.Unlock()
+	for s := range r.subs {
+		select {
+		case s.ch <- data:
+			continue
+		default:
+		}
+
+		switch s.policy {
+		case OverflowBlock:
+			select {
+			case s.ch <- data:
+			case <-s.done:
+			case <-r.closing:
+			}
+		case OverflowDropOldest:
+			select {
+			case <-s.ch:
+			default:
+			}
+			s.ch <- data // Only publish sends, so the slot just freed is still free
+			r.dropped(s)
+		case OverflowDropNewest:
+			r.dropped(s)
+		case OverflowError:
+			r.dropped(s)
+			r.removeSubscriber(s, ErrSubscriberOverflow)
+		}
+	}
+}
+
+// dropped counts an update discarded for s.
+func (r *Resource) dropped(s *Subscription) {
+	atomic.AddUint64(&s.dropped, 1)
+	r.metrics.droppedNotifications.Add(1)
+}
+
+// removeSubscriber unregisters s and closes its channel, recording err as the reason. r.subsMu must be held.
+func (r *Resource) removeSubscriber(s *Subscription, err error) {
+	delete(r.subs, s)
+	if !s.closed {
+		s.closed = true
+		s.err = err
+		close(s.ch)
+	}
+}
+
+// DrainMode selects what DrainNotifications returns.
+type DrainMode int
+
+const (
+	// DrainAll returns every buffered update in the order it was written.
+	DrainAll DrainMode = iota
+	// DrainLatest collapses the buffered updates into the most recent one.
+	DrainLatest
+)
+
+// DrainNotifications removes every update currently buffered for the subscription and returns
+// them at once, without blocking, for consumers that poll in batches. Under DrainLatest only the
+// newest update is returned. The result is empty if nothing was buffered.
+func (s *Subscription) DrainNotifications(mode DrainMode) []string {
+	var updates []string
+	for {
+		select {
+		case data, ok := <-s.ch:
+			if !ok {
+				return updates // The subscription has ended
+			}
+			if mode == DrainLatest {
+				updates = updates[:0]
+			}
+			updates = append(updates, data)
+		default:
+			return updates
+		}
+	}
+}
+
+// ErrLossyOverflow is returned by ReadAndSubscribeWith for an overflow policy that drops updates.
+var ErrLossyOverflow = errors.New("overflow policy drops updates")
+
+// ReadAndSubscribe returns the current data together with a channel that delivers every later write,
+// with no gap or duplicate between the two, and a function that ends delivery. The subscription uses
+// OverflowBlock, so every write is delivered, but writers wait while its buffer is full; a consumer
+// that stops reading must call unsubscribe. Use ReadAndSubscribeWith to choose the buffer size or
+// OverflowError instead.
+func (r *Resource) ReadAndSubscribe(ctx context.Context) (current string, updates <-chan string, unsubscribe func(), err error) {
+	current, sub, err := r.ReadAndSubscribeWith(ctx, SubscribeOptions{Overflow: OverflowBlock})
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return current, sub.C, sub.Unsubscribe, nil
+}
+
+// ReadAndSubscribeWith is ReadAndSubscribe with a subscription configured by opts. The subscription is
+// registered under the read lock, so no write can land between the read and the start of delivery.
+// Policies that drop updates would break that guarantee, so they fail with ErrLossyOverflow. Under
+// OverflowError a lost update ends the subscription instead and is reported by its Err method.
+func (r *Resource) ReadAndSubscribeWith(ctx context.Context, opts SubscribeOptions) (current string, sub *Subscription, err error) {
+	if opts.Overflow == OverflowDropOldest || opts.Overflow == OverflowDropNewest {
+		return "", nil, ErrLossyOverflow
+	}
+	ctx, cancel := r.withDefaultTimeout(ctx, OpRead)
+	defer cancel()
+
+	ev := r.startEvent(ctx, OpRead)
+	err = r.withReadLock(ctx, &ev, func(data string) error {
+		current = data
+		sub = r.Subscribe(opts)
+		return nil
+	})
+	ev.Value = current
+	r.finishEvent(ev, err)
+	if err != nil {
+		return "", nil, err
+	}
+	return current, sub, nil
+}
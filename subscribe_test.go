@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestReadAndSubscribeLosesNoUpdate(t *testing.T) {
+	r := NewResource("initial")
+	ctx := context.Background()
+
+	current, updates, unsubscribe, err := r.ReadAndSubscribe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+	if current != "initial" {
+		t.Fatalf("got current %q", current)
+	}
+	for i := 0; i < 3; i++ {
+		if err := r.Write(ctx, fmt.Sprint(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case got := <-updates:
+			if got != fmt.Sprint(i) {
+				t.Fatalf("update %d: got %q", i, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("update %d was lost", i)
+		}
+	}
+}
+
+func TestReadAndSubscribeSlowConsumerDoesNotStallWriters(t *testing.T) {
+	r := NewResource("initial")
+	ctx := context.Background()
+
+	_, sub, err := r.ReadAndSubscribeWith(ctx, SubscribeOptions{Buffer: 1, Overflow: OverflowError})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < 5; i++ {
+			if err := r.Write(ctx, fmt.Sprint(i)); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("writers stalled behind a subscriber that never reads")
+	}
+	if err := sub.Err(); !errors.Is(err, ErrSubscriberOverflow) {
+		t.Fatalf("got %v, want the lost update reported as ErrSubscriberOverflow", err)
+	}
+}
+
+func TestReadAndSubscribeRejectsLossyOverflow(t *testing.T) {
+	r := NewResource("initial")
+	for _, policy := range []OverflowPolicy{OverflowDropOldest, OverflowDropNewest} {
+		if _, _, err := r.ReadAndSubscribeWith(context.Background(), SubscribeOptions{Overflow: policy}); !errors.Is(err, ErrLossyOverflow) {
+			t.Errorf("policy %d: got %v, want ErrLossyOverflow", policy, err)
+		}
+	}
+	if n := len(r.subs); n != 0 {
+		t.Fatalf("%d subscriptions registered by rejected calls", n)
+	}
+}
+
+// writeAll writes each value to r in order.
+func writeAll(t *testing.T, r *Resource, values ...string) {
+	t.Helper()
+	for _, v := range values {
+		if err := r.Write(context.Background(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestOverflowDropPolicies(t *testing.T) {
+	tests := []struct {
+		policy OverflowPolicy
+		kept   []string
+	}{
+		{OverflowDropOldest, []string{"3", "4"}},
+		{OverflowDropNewest, []string{"1", "2"}},
+	}
+	for _, tt := range tests {
+		r := NewResource("initial")
+		sub := r.Subscribe(SubscribeOptions{Buffer: 2, Overflow: tt.policy})
+		writeAll(t, r, "1", "2", "3", "4") // The subscriber reads nothing until every write is done
+
+		got := sub.DrainNotifications(DrainAll)
+		if fmt.Sprint(got) != fmt.Sprint(tt.kept) {
+			t.Errorf("policy %d: kept %v, want %v", tt.policy, got, tt.kept)
+		}
+		if sub.Dropped() != 2 || r.Metrics().DroppedNotifications != 2 {
+			t.Errorf("policy %d: dropped %d (resource %d), want 2", tt.policy, sub.Dropped(), r.Metrics().DroppedNotifications)
+		}
+	}
+}
+
+func TestOverflowErrorEndsSubscription(t *testing.T) {
+	r := NewResource("initial")
+	sub := r.Subscribe(SubscribeOptions{Buffer: 1, Overflow: OverflowError})
+	writeAll(t, r, "1", "2", "3")
+
+	if got := sub.DrainNotifications(DrainAll); fmt.Sprint(got) != "[1]" {
+		t.Fatalf("got %v, want only the update buffered before the overflow", got)
+	}
+	if _, ok := <-sub.C; ok {
+		t.Fatal("subscription still open after overflowing")
+	}
+	if !errors.Is(sub.Err(), ErrSubscriberOverflow) {
+		t.Fatalf("got %v, want ErrSubscriberOverflow", sub.Err())
+	}
+}
+
+func TestOverflowBlockWaitsForSlowSubscriber(t *testing.T) {
+	r := NewResource("initial")
+	sub := r.Subscribe(SubscribeOptions{Buffer: 1, Overflow: OverflowBlock})
+	defer sub.Unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		for _, v := range []string{"1", "2", "3"} {
+			r.Write(context.Background(), v)
+		}
+		close(done)
+	}()
+	var got []string
+	for len(got) < 3 {
+		time.Sleep(10 * time.Millisecond) // A slow consumer
+		select {
+		case <-done:
+			if len(got) < 2 {
+				t.Fatal("writer finished before the subscriber caught up")
+			}
+		default:
+		}
+		got = append(got, <-sub.C)
+	}
+	<-done
+	if fmt.Sprint(got) != "[1 2 3]" || sub.Dropped() != 0 {
+		t.Fatalf("got %v with %d dropped, want every update delivered", got, sub.Dropped())
+	}
+}
+
+func TestOverflowBlockReleasedByUnsubscribe(t *testing.T) {
+	r := NewResource("initial")
+	sub := r.Subscribe(SubscribeOptions{Buffer: 1, Overflow: OverflowBlock})
+	writeAll(t, r, "1")
+
+	done := make(chan struct{})
+	go func() {
+		r.Write(context.Background(), "2")
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	sub.Unsubscribe()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("writer still blocked after the subscriber left")
+	}
+}
+
+func TestDrainNotifications(t *testing.T) {
+	r := NewResource("initial")
+	all := r.Subscribe(SubscribeOptions{})
+	latest := r.Subscribe(SubscribeOptions{})
+	writeAll(t, r, "1", "2", "3")
+
+	if got := all.DrainNotifications(DrainAll); fmt.Sprint(got) != "[1 2 3]" {
+		t.Fatalf("DrainAll got %v", got)
+	}
+	if got := latest.DrainNotifications(DrainLatest); fmt.Sprint(got) != "[3]" {
+		t.Fatalf("DrainLatest got %v", got)
+	}
+	if got := all.DrainNotifications(DrainAll); len(got) != 0 {
+		t.Fatalf("second drain got %v, want nothing left", got)
+	}
+
+	all.Unsubscribe()
+	if got := all.DrainNotifications(DrainAll); len(got) != 0 {
+		t.Fatalf("drain after unsubscribe got %v", got)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Granularity and span of the success rate window.
+const (
+	rateBucketWidth = time.Second
+	rateBuckets     = 60
+)
+
+// successWindow counts operation outcomes in a ring of time buckets, bounding memory
+// while keeping recent success rates cheap to compute.
+type successWindow struct {
+	mu      sync.Mutex
+	buckets [rateBuckets]rateBucket
+}
+
+// rateBucket holds the outcomes recorded during one bucket-width interval.
+type rateBucket struct {
+	epoch     int64 // Interval index the counts belong to
+	succeeded uint64
+	total     uint64
+}
+
+// record counts an outcome at time now.
+func (w *successWindow) record(now time.Time, err error) {
+	epoch := now.UnixNano() / int64(rateBucketWidth)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	b := &w.buckets[epoch%rateBuckets]
+	if b.epoch != epoch {
+		*b = rateBucket{epoch: epoch} // Reuse a bucket left over from an older interval
+	}
+	b.total++
+	if err == nil {
+		b.succeeded++
+	}
+}
+
+// rate returns the fraction of successful outcomes within window of now.
+func (w *successWindow) rate(now time.Time, window time.Duration) float64 {
+	n := int64((window + rateBucketWidth - 1) / rateBucketWidth) // Buckets covering the window, including the current one
+	if n < 1 {
+		n = 1
+	} else if n > rateBuckets {
+		n = rateBuckets
+	}
+	newest := now.UnixNano() / int64(rateBucketWidth)
+	oldest := newest - n + 1
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var succeeded, total uint64
+	for _, b := range w.buckets {
+		if b.epoch >= oldest && b.epoch <= newest {
+			succeeded += b.succeeded
+			total += b.total
+		}
+	}
+	if total == 0 {
+		return 1 // No operations means nothing has failed
+	}
+	return float64(succeeded) / float64(total)
+}
+
+// SuccessRate returns the fraction of operations that succeeded within the recent window,
+// at one-second granularity and for windows up to one minute. It returns 1 if no operation ran.
+func (r *Resource) SuccessRate(window time.Duration) float64 {
+	return r.metrics.window.rate(time.Now(), window)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSuccessWindowRate(t *testing.T) {
+	var w successWindow
+	base := time.Unix(1000, 0)
+	errBusy := errors.New("busy")
+
+	for i := 0; i < 3; i++ {
+		w.record(base.Add(-30*time.Second), nil)
+	}
+	w.record(base, nil)
+	w.record(base, errBusy)
+
+	tests := []struct {
+		window time.Duration
+		want   float64
+	}{
+		{time.Second, 0.5},      // Only the current bucket
+		{time.Minute, 0.8},      // Every outcome
+		{10 * time.Second, 0.5}, // The older successes fall outside
+	}
+	for _, tt := range tests {
+		if got := w.rate(base, tt.window); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("window %v: got %v, want %v", tt.window, got, tt.want)
+		}
+	}
+
+	// A minute later the ring has wrapped and the old outcomes no longer count
+	later := base.Add(rateBuckets * rateBucketWidth)
+	w.record(later, errBusy)
+	if got := w.rate(later, time.Minute); got != 0 {
+		t.Fatalf("after wrapping: got %v, want only the new failure counted", got)
+	}
+}
+
+func TestSuccessRateOfResource(t *testing.T) {
+	r := NewResource("initial")
+	if got := r.SuccessRate(time.Minute); got != 1 {
+		t.Fatalf("idle resource: got %v, want 1", got)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		r.Read(ctx)
+	}
+	r.SetReadOnly(true)
+	r.Write(ctx, "rejected")
+	if got := r.SuccessRate(time.Minute); math.Abs(got-0.75) > 1e-9 {
+		t.Fatalf("got %v, want 3 of 4 operations successful", got)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// adaptiveAlpha is the weight given to the newest latency sample in the moving average.
+const adaptiveAlpha = 0.2
+
+// adaptiveFloor is the smallest timeout the controller hands out, even when Min is zero.
+// A zero timeout fails every operation immediately and doubling it never recovers.
+const adaptiveFloor = time.Millisecond
+
+// AdaptiveTimeout adjusts the per-operation timeout based on a moving average of recent latencies.
+// Timeouts grow while operations are timing out and shrink back as operations succeed quickly.
+type AdaptiveTimeout struct {
+	Min time.Duration // Lower bound for the timeout
+	Max time.Duration // Upper bound for the timeout
+
+	mu      sync.Mutex
+	avg     time.Duration // Moving average of recent latencies
+	current time.Duration // Timeout applied to the next operation
+}
+
+// NewAdaptiveTimeout creates a new AdaptiveTimeout starting at initial and bounded by min and max.
+func NewAdaptiveTimeout(initial, min, max time.Duration) *AdaptiveTimeout {
+	a := &AdaptiveTimeout{Min: min, Max: max}
+	a.current = a.clamp(initial)
+	return a
+}
+
+// Timeout returns the timeout to apply to the next operation.
+func (a *AdaptiveTimeout) Timeout() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.current
+}
+
+// Observe records the latency and outcome of a completed operation and adjusts the timeout.
+func (a *AdaptiveTimeout) Observe(latency time.Duration, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		// The operation ran out of time, so back off up to the cap
+		a.current = a.clamp(a.current * 2)
+		return
+	}
+
+	if a.avg == 0 {
+		a.avg = latency
+	} else {
+		a.avg = time.Duration(adaptiveAlpha*float64(latency) + (1-adaptiveAlpha)*float64(a.avg))
+	}
+	// Leave headroom above the average so normal jitter does not cause timeouts
+	a.current = a.clamp(a.avg * 2)
+}
+
+// clamp bounds d to the configured minimum and maximum, never going below adaptiveFloor.
+func (a *AdaptiveTimeout) clamp(d time.Duration) time.Duration {
+	if d < a.Min {
+		d = a.Min
+	}
+	if d < adaptiveFloor {
+		d = adaptiveFloor
+	}
+	if a.Max > 0 && d > a.Max {
+		return a.Max
+	}
+	return d
+}
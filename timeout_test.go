@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveTimeoutBacksOffOnDeadline(t *testing.T) {
+	a := NewAdaptiveTimeout(10*time.Millisecond, time.Millisecond, 50*time.Millisecond)
+
+	a.Observe(10*time.Millisecond, context.DeadlineExceeded)
+	if got := a.Timeout(); got != 20*time.Millisecond {
+		t.Fatalf("after one timeout: got %v, want 20ms", got)
+	}
+	for i := 0; i < 5; i++ {
+		a.Observe(a.Timeout(), context.DeadlineExceeded)
+	}
+	if got := a.Timeout(); got != 50*time.Millisecond {
+		t.Fatalf("after repeated timeouts: got %v, want the 50ms cap", got)
+	}
+}
+
+func TestAdaptiveTimeoutShrinksOnFastSuccess(t *testing.T) {
+	a := NewAdaptiveTimeout(40*time.Millisecond, time.Millisecond, time.Second)
+
+	for i := 0; i < 20; i++ {
+		a.Observe(2*time.Millisecond, nil)
+	}
+	if got := a.Timeout(); got != 4*time.Millisecond {
+		t.Fatalf("got %v, want twice the 2ms average", got)
+	}
+}
+
+func TestAdaptiveTimeoutZeroMinRecovers(t *testing.T) {
+	a := NewAdaptiveTimeout(0, 0, time.Second)
+	if got := a.Timeout(); got <= 0 {
+		t.Fatalf("initial timeout %v, want a positive floor", got)
+	}
+
+	for i := 0; i < 5; i++ {
+		a.Observe(a.Timeout(), context.DeadlineExceeded)
+	}
+	if got := a.Timeout(); got < 32*adaptiveFloor {
+		t.Fatalf("after five timeouts: got %v, want at least %v", got, 32*adaptiveFloor)
+	}
+
+	a.Observe(0, nil)
+	if got := a.Timeout(); got < adaptiveFloor {
+		t.Fatalf("after a zero-latency success: got %v, want at least %v", got, adaptiveFloor)
+	}
+}
+
+func TestAdaptiveTimeoutGrowsWithRisingLatency(t *testing.T) {
+	a := NewAdaptiveTimeout(time.Millisecond, time.Millisecond, time.Second)
+
+	prev := a.Timeout()
+	for latency := 5 * time.Millisecond; latency <= 80*time.Millisecond; latency *= 2 {
+		for i := 0; i < 5; i++ {
+			a.Observe(latency, nil)
+		}
+		if got := a.Timeout(); got <= prev {
+			t.Fatalf("latency rose to %v but the timeout went from %v to %v", latency, prev, got)
+		}
+		prev = a.Timeout()
+	}
+	if prev <= 80*time.Millisecond {
+		t.Fatalf("timeout %v leaves no headroom above the latest 80ms latency", prev)
+	}
+}
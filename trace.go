@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// traceEvent is a duration event in the Chrome Trace Event format.
+type traceEvent struct {
+	Name  string            `json:"name"`
+	Phase string            `json:"ph"`
+	TS    int64             `json:"ts"`  // Start in microseconds since the earliest event
+	Dur   int64             `json:"dur"` // Duration in microseconds
+	PID   int               `json:"pid"`
+	TID   int               `json:"tid"` // Worker ID
+	Args  map[string]string `json:"args,omitempty"`
+}
+
+// ExportTrace writes the run's event timeline in the Chrome Trace Event format, for viewing in chrome://tracing.
+// Each worker is shown as its own thread and each operation as a duration event.
+func (s SimulationResult) ExportTrace(w io.Writer) error {
+	trace := struct {
+		TraceEvents []traceEvent `json:"traceEvents"`
+	}{TraceEvents: make([]traceEvent, 0, len(s.Events))}
+
+	// Events may be in completion order, so find the earliest start rather than taking the first
+	var origin time.Time
+	for i, ev := range s.Events {
+		if i == 0 || ev.Start.Before(origin) {
+			origin = ev.Start
+		}
+	}
+
+	for _, ev := range s.Events {
+		args := map[string]string{
+			"worker": ev.Worker.String(),
+			"op_id":  strconv.FormatUint(ev.OpID, 10),
+		}
+		if ev.ParentID != 0 {
+			args["parent_id"] = strconv.FormatUint(ev.ParentID, 10)
+		}
+		if ev.Err != nil {
+			args["error"] = ev.Err.Error()
+		}
+		trace.TraceEvents = append(trace.TraceEvents, traceEvent{
+			Name:  ev.Op,
+			Phase: "X",
+			TS:    ev.Start.Sub(origin).Microseconds(),
+			Dur:   ev.Duration.Microseconds(),
+			PID:   1,
+			TID:   ev.Worker.ID,
+			Args:  args,
+		})
+	}
+	return json.NewEncoder(w).Encode(trace)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// parsedTrace is the subset of the Chrome trace format checked by the tests.
+type parsedTrace struct {
+	TraceEvents []struct {
+		Name  string `json:"name"`
+		Phase string `json:"ph"`
+		TS    int64  `json:"ts"`
+		TID   int    `json:"tid"`
+	} `json:"traceEvents"`
+}
+
+func TestExportTraceFromSimulation(t *testing.T) {
+	result, err := RunSimulation(3, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := result.ExportTrace(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var trace parsedTrace
+	if err := json.Unmarshal(buf.Bytes(), &trace); err != nil {
+		t.Fatalf("trace is not valid JSON: %v", err)
+	}
+	if got, want := len(trace.TraceEvents), len(result.Events); got != want {
+		t.Fatalf("got %d trace events, want %d", got, want)
+	}
+	for _, ev := range trace.TraceEvents {
+		if ev.Phase != "X" || ev.TID < 1 || ev.TID > 3 {
+			t.Errorf("unexpected trace event %+v", ev)
+		}
+	}
+}
+
+func TestExportTraceCompletionOrder(t *testing.T) {
+	start := time.Now()
+	result := SimulationResult{Events: []Event{
+		{Op: OpWrite, Start: start.Add(5 * time.Millisecond), Duration: time.Millisecond},
+		{Op: OpRead, Start: start, Duration: 10 * time.Millisecond}, // Started first, finished last
+	}}
+
+	var buf bytes.Buffer
+	if err := result.ExportTrace(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var trace parsedTrace
+	if err := json.Unmarshal(buf.Bytes(), &trace); err != nil {
+		t.Fatal(err)
+	}
+	if trace.TraceEvents[0].TS != 5000 || trace.TraceEvents[1].TS != 0 {
+		t.Fatalf("got timestamps %d and %d, want 5000 and 0", trace.TraceEvents[0].TS, trace.TraceEvents[1].TS)
+	}
+}
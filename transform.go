@@ -0,0 +1,54 @@
+package main
+
+// Transformer converts data on its way into or out of a resource, such as normalizing values
+// on write or redacting them on read. Hooks run inside the lock of the operation they apply to.
+type Transformer struct {
+	OnWrite func(data string) (string, error) // Applied to new data before it is stored; nil leaves it unchanged
+	OnRead  func(data string) (string, error) // Applied to stored data before it is returned; nil leaves it unchanged
+}
+
+// WithTransformers appends ts to the resource's transformer chain.
+// OnWrite hooks run in chain order and OnRead hooks in reverse order, so each transformer
+// sees on read the form it produced on write. A hook error fails the operation.
+// Read-modify-write operations such as Patch, Swap and Increment see the data through the
+// OnRead hooks, the same way Read and Update do.
+func WithTransformers(ts ...Transformer) ResourceOption {
+	return func(r *Resource) {
+		r.transformers = append(r.transformers, ts...)
+	}
+}
+
+// transformWrite passes data through the chain's OnWrite hooks.
+func (r *Resource) transformWrite(data string) (string, error) {
+	for _, t := range r.transformers {
+		if t.OnWrite == nil {
+			continue
+		}
+		if err := callSafely(func() error {
+			var err error
+			data, err = t.OnWrite(data)
+			return err
+		}); err != nil {
+			return "", err
+		}
+	}
+	return data, nil
+}
+
+// transformRead passes data through the chain's OnRead hooks in reverse order.
+func (r *Resource) transformRead(data string) (string, error) {
+	for i := len(r.transformers) - 1; i >= 0; i-- {
+		t := r.transformers[i]
+		if t.OnRead == nil {
+			continue
+		}
+		if err := callSafely(func() error {
+			var err error
+			data, err = t.OnRead(data)
+			return err
+		}); err != nil {
+			return "", err
+		}
+	}
+	return data, nil
+}
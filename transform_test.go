@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// redactSecrets is a transformer that hides values containing "secret" from readers.
+var redactSecrets = Transformer{
+	OnRead: func(data string) (string, error) {
+		if strings.Contains(data, "secret") {
+			return "REDACTED", nil
+		}
+		return data, nil
+	},
+}
+
+// normalize is a transformer that trims and lower-cases values on write.
+var normalize = Transformer{
+	OnWrite: func(data string) (string, error) {
+		return strings.ToLower(strings.TrimSpace(data)), nil
+	},
+}
+
+func TestTransformersNormalizeAndRedact(t *testing.T) {
+	r := NewResource("", WithTransformers(normalize, redactSecrets))
+	ctx := context.Background()
+
+	if err := r.Write(ctx, "  Hello "); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := r.Read(ctx); data != "hello" {
+		t.Fatalf("got %q, want the normalized \"hello\"", data)
+	}
+	if err := r.Write(ctx, "My SECRET"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := r.Read(ctx); data != "REDACTED" {
+		t.Fatalf("got %q, want the value redacted", data)
+	}
+}
+
+func TestTransformersApplyToEveryRead(t *testing.T) {
+	ctx := context.Background()
+	newSecret := func() *Resource {
+		return NewResource("secret", WithTransformers(redactSecrets))
+	}
+
+	if old, err := newSecret().Swap(ctx, "next"); err != nil || old != "REDACTED" {
+		t.Errorf("Swap returned %q (%v)", old, err)
+	}
+
+	var seen string
+	r := newSecret()
+	r.Patch(ctx, func(current string) (string, bool) {
+		seen = current
+		return "", false
+	})
+	if seen != "REDACTED" {
+		t.Errorf("Patch callback saw %q", seen)
+	}
+
+	r = newSecret()
+	if err := r.WriteAndDowngrade(ctx, "new secret", func(data string) error {
+		seen = data
+		return nil
+	}); err != nil || seen != "REDACTED" {
+		t.Errorf("WriteAndDowngrade callback saw %q (%v)", seen, err)
+	}
+
+	snap, release, err := newSecret().BeginSnapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if snap.Data != "REDACTED" {
+		t.Errorf("snapshot holds %q", snap.Data)
+	}
+
+	r = NewResource("1", WithTransformers(Transformer{
+		OnRead: func(data string) (string, error) { return "1" + data, nil }, // Readers see a leading 1
+	}))
+	if n, err := r.Increment(ctx, 1); err != nil || n != 12 {
+		t.Errorf("Increment returned %d (%v), want 11+1", n, err)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrTxDone is returned by operations on a transaction that has already been committed or rolled back.
+var ErrTxDone = errors.New("transaction has already been committed or rolled back")
+
+// Tx is a multi-step transaction holding the resource's write lock from Begin until Commit or Rollback.
+// Values set in the transaction are only stored on Commit, so Rollback leaves the pre-transaction
+// value in place. If the context passed to Begin is done first, the transaction is rolled back
+// and its remaining calls return the context error.
+type Tx struct {
+	resource  *Resource
+	ctx       context.Context
+	cancel    context.CancelFunc
+	ev        Event
+	stopAbort func() bool // Cancels the deadline-driven rollback; set under mu
+
+	mu    sync.Mutex // Guards the fields below
+	value string     // Value set by the transaction
+	dirty bool       // Whether Set has been called
+	done  bool       // Whether the write lock has been released
+	err   error      // Returned by calls made after the transaction ended
+}
+
+// Begin starts a transaction, acquiring the write lock. The caller must end it with Commit or Rollback.
+func (r *Resource) Begin(ctx context.Context) (*Tx, error) {
+	ctx, cancel := r.withDefaultTimeout(ctx, OpWrite)
+	ev := r.startEvent(ctx, OpWrite)
+	if err := r.lockForWrite(ctx, &ev); err != nil {
+		cancel()
+		r.finishEvent(ev, err)
+		return nil, err
+	}
+	tx := &Tx{resource: r, ctx: ctx, cancel: cancel, ev: ev}
+	tx.mu.Lock() // The rollback may run at once if ctx is already done, so make it wait for stopAbort
+	tx.stopAbort = context.AfterFunc(ctx, func() { tx.end(ctx.Err()) })
+	tx.mu.Unlock()
+	return tx, nil
+}
+
+// Get returns the value set in the transaction, or the resource's data if none has been set.
+func (tx *Tx) Get() (string, error) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return "", tx.err
+	}
+	if tx.dirty {
+		return tx.value, nil
+	}
+	return tx.resource.transformRead(tx.resource.data)
+}
+
+// Set replaces the value to be stored when the transaction commits.
+func (tx *Tx) Set(value string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return tx.err
+	}
+	tx.value, tx.dirty = value, true
+	return nil
+}
+
+// Commit stores the value set in the transaction, if any, and releases the write lock.
+func (tx *Tx) Commit() error {
+	tx.mu.Lock()
+	if tx.done {
+		tx.mu.Unlock()
+		return tx.err
+	}
+	var err error
+	if tx.dirty {
+		value := tx.value
+		tx.ev.Value, err = tx.resource.apply(tx.ctx, func(string) (string, error) {
+			return value, nil
+		})
+		tx.resource.finishEvent(tx.ev, err)
+	}
+	tx.mu.Unlock()
+	tx.end(ErrTxDone)
+	return err
+}
+
+// Rollback discards the value set in the transaction and releases the write lock.
+func (tx *Tx) Rollback() error {
+	tx.mu.Lock()
+	done, err := tx.done, tx.err
+	tx.mu.Unlock()
+	if done {
+		return err
+	}
+	tx.end(ErrTxDone)
+	return nil
+}
+
+// end releases the write lock once, recording err as the result of later calls.
+func (tx *Tx) end(err error) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return
+	}
+	tx.done, tx.err = true, err
+	tx.stopAbort()
+	tx.resource.untrack(tx.ev) // No event is recorded if nothing was committed
+	tx.resource.mu.Unlock()
+	tx.cancel()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTxCommit(t *testing.T) {
+	r := NewResource("initial")
+	tx, err := r.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Set("committed"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := tx.Get(); got != "committed" {
+		t.Fatalf("Get inside the transaction: got %q", got)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := r.Read(context.Background()); data != "committed" || r.Version() != 1 {
+		t.Fatalf("got %q at version %d after commit", data, r.Version())
+	}
+	if err := tx.Set("late"); !errors.Is(err, ErrTxDone) {
+		t.Fatalf("Set after commit: got %v, want ErrTxDone", err)
+	}
+}
+
+func TestTxRollback(t *testing.T) {
+	r := NewResource("initial")
+	tx, err := r.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Set("discarded")
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := r.Read(context.Background()); data != "initial" || r.Version() != 0 {
+		t.Fatalf("got %q at version %d after rollback", data, r.Version())
+	}
+	if err := tx.Commit(); !errors.Is(err, ErrTxDone) {
+		t.Fatalf("Commit after rollback: got %v, want ErrTxDone", err)
+	}
+}
+
+func TestTxDeadlineAborts(t *testing.T) {
+	r := NewResource("initial")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	tx, err := r.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Set("too late")
+
+	<-ctx.Done()
+	if err := r.Write(context.Background(), "after"); err != nil { // The lock must have been released
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Commit after the deadline: got %v, want DeadlineExceeded", err)
+	}
+	if data, _ := r.Read(context.Background()); data != "after" {
+		t.Fatalf("got %q, want the aborted transaction discarded", data)
+	}
+}
+
+func TestTxBeginRacingDeadline(t *testing.T) {
+	r := NewResource("initial")
+	for i := 0; i < 2000; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(i%20)*time.Microsecond)
+		tx, err := r.Begin(ctx)
+		if err == nil {
+			tx.Rollback()
+		}
+		cancel()
+	}
+	if err := r.Write(context.Background(), "still usable"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+// ChangeValidator checks a transition from old to new data before it is stored, returning an error
+// to reject it. It runs inside the write lock, so it can enforce invariants spanning both values,
+// such as a counter never decreasing.
+type ChangeValidator func(old, new string) error
+
+// WithChangeValidator adds v to the validators run on every write. A write rejected by any
+// validator is aborted with the validator's error and leaves the data unchanged.
+func WithChangeValidator(v ChangeValidator) ResourceOption {
+	return func(r *Resource) {
+		r.validators = append(r.validators, v)
+	}
+}
+
+// validateChange runs every validator on the transition from the current data to newData.
+// The write lock must be held.
+func (r *Resource) validateChange(newData string) error {
+	for _, v := range r.validators {
+		if err := callSafely(func() error { return v(r.data, newData) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
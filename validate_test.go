@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+// errDecrease is returned by nonDecreasing for a write that would lower the counter.
+var errDecrease = errors.New("counter may not decrease")
+
+// nonDecreasing is a change validator that rejects writes lowering a numeric value.
+func nonDecreasing(old, new string) error {
+	o, _ := strconv.Atoi(old)
+	n, err := strconv.Atoi(new)
+	if err != nil {
+		return err
+	}
+	if n < o {
+		return fmt.Errorf("%w: %d to %d", errDecrease, o, n)
+	}
+	return nil
+}
+
+func TestChangeValidatorRejectsDecrease(t *testing.T) {
+	r := NewResource("5", WithChangeValidator(nonDecreasing))
+	ctx := context.Background()
+
+	if err := r.Write(ctx, "3"); !errors.Is(err, errDecrease) {
+		t.Fatalf("decreasing write got %v, want the validator's error", err)
+	}
+	if data, _ := r.Read(ctx); data != "5" || r.Version() != 0 {
+		t.Fatalf("rejected write changed the data to %q", data)
+	}
+	if err := r.Write(ctx, "8"); err != nil {
+		t.Fatalf("increasing write: %v", err)
+	}
+	if _, err := r.Increment(ctx, -1); !errors.Is(err, errDecrease) {
+		t.Fatalf("decrement got %v, want it validated like any write", err)
+	}
+	if data, _ := r.Read(ctx); data != "8" {
+		t.Fatalf("got %q", data)
+	}
+}
+
+func TestChangeValidatorsAllRun(t *testing.T) {
+	errOdd := errors.New("odd value")
+	r := NewResource("0",
+		WithChangeValidator(nonDecreasing),
+		WithChangeValidator(func(_, new string) error {
+			if n, _ := strconv.Atoi(new); n%2 != 0 {
+				return errOdd
+			}
+			return nil
+		}),
+	)
+	if err := r.Write(context.Background(), "3"); !errors.Is(err, errOdd) {
+		t.Fatalf("got %v, want the second validator to reject", err)
+	}
+}
@@ -2,124 +2,856 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Resource represents a shared resource that can be read from or written to.
 type Resource struct {
-	data string
-	mu   sync.RWMutex // Mutex for read-write synchronization
+	id         uint64 // Process-wide unique ID, used to order lock acquisition across resources
+	data       string
+	lastWriter WorkerIdentity // Identity of the client behind the latest write
+	mu         ResourceLock   // Context-aware lock for read-write synchronization
+
+	defaultTimeout      time.Duration // Timeout applied to operations whose context has no deadline
+	readTimeout         time.Duration // Overrides defaultTimeout for reads
+	writeTimeout        time.Duration // Overrides defaultTimeout for writes
+	readOnly            int32         // Set to 1 while writes are rejected with ErrReadOnly; changed under modeMu
+	maxBytes            int64         // Memory limit enforced on writes; zero means unlimited
+	valueBytes          int64         // Size of data, readable without the lock
+	starvationThreshold time.Duration // Lock wait above which a write is reported as starved
+	starvedWrites       uint64        // Number of writes that exceeded the starvation threshold
+	slowThreshold       int64         // Duration above which operations are logged as slow; zero disables
+
+	transformers []Transformer     // Hooks applied to data on write and read
+	validators   []ChangeValidator // Checks run on every transition before it is stored
+
+	leaseMu   sync.Mutex           // Guards leases
+	leases    map[uint64]time.Time // Expiry of each active read lease
+	nextLease uint64               // ID assigned to the most recent lease
+
+	timeline    int32      // Set to 1 while completed operations are recorded in events
+	eventsMu    sync.Mutex // Guards events
+	events      []Event    // Timeline of completed operations; empty unless the timeline is enabled
+	eventsBytes int64      // Bytes of values held by events
+
+	flightMu sync.Mutex               // Guards inFlight and landed
+	inFlight map[*inFlightOp]struct{} // Operations started but not yet finished
+	landed   chan struct{}            // Closed when an operation finishes; nil until someone waits
+
+	metrics resourceMetrics            // Operation counters
+	opLog   *operationLog              // Ring buffer of recent operations; nil if disabled
+	cache   atomic.Pointer[cachedRead] // Latest value served by ReadWithin
+
+	priority *priorityQueue      // Serializer for prioritized operations; nil if disabled
+	newLock  func() ResourceLock // Creates mu; nil uses NewRWLock
+
+	idempotency *idempotencyCache // Keys of recently applied writes; nil if writes are not deduplicated
+
+	modeMu      sync.Mutex    // Guards modeChanged and changes to readOnly
+	modeChanged chan struct{} // Closed when read-only mode changes; nil until someone waits
+
+	notifyMu  sync.Mutex       // Guards version and changed
+	version   uint64           // Number of successful writes
+	lastWrite time.Time        // Time of the latest write, or of creation if never written
+	changed   chan struct{}    // Closed on the next write; nil until someone waits
+	writers   map[int]struct{} // IDs of the distinct workers that have written
+
+	subsMu sync.Mutex                 // Guards subs
+	subs   map[*Subscription]struct{} // Subscribers notified of every write
+
+	streamsMu sync.Mutex              // Guards streams
+	streams   map[chan Event]struct{} // Event streams fed every completed operation
+
+	closed     int32                             // Set to 1 once Close has been called
+	closing    chan struct{}                     // Closed by Close to release writers blocked on subscribers
+	hooksMu    sync.Mutex                        // Guards closeHooks
+	closeHooks []func(ctx context.Context) error // Cleanup run by Close, in reverse order
+}
+
+// ResourceOption configures optional behavior of a Resource.
+type ResourceOption func(*Resource)
+
+// WithStarvationThreshold reports a warning whenever a write waits longer than threshold to acquire the lock.
+func WithStarvationThreshold(threshold time.Duration) ResourceOption {
+	return func(r *Resource) {
+		r.starvationThreshold = threshold
+	}
+}
+
+// WithDefaultTimeout bounds every operation whose context has no deadline by timeout.
+// This guards against unbounded lock waits when a caller passes context.Background().
+func WithDefaultTimeout(timeout time.Duration) ResourceOption {
+	return func(r *Resource) {
+		r.defaultTimeout = timeout
+	}
+}
+
+// WithReadTimeout bounds every read whose context has no deadline by timeout, overriding WithDefaultTimeout.
+func WithReadTimeout(timeout time.Duration) ResourceOption {
+	return func(r *Resource) {
+		r.readTimeout = timeout
+	}
+}
+
+// WithWriteTimeout bounds every write whose context has no deadline by timeout, overriding WithDefaultTimeout.
+func WithWriteTimeout(timeout time.Duration) ResourceOption {
+	return func(r *Resource) {
+		r.writeTimeout = timeout
+	}
 }
 
 // NewResource creates a new instance of Resource.
-func NewResource(data string) *Resource {
-	return &Resource{data: data}
+func NewResource(data string, opts ...ResourceOption) *Resource {
+	r := &Resource{id: nextResourceID(), data: data, lastWrite: time.Now(), valueBytes: int64(len(data)), closing: make(chan struct{})}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.mu = r.createLock()
+	if r.priority != nil {
+		r.startPriorityQueue()
+	}
+	return r
+}
+
+// createLock returns a new lock from the configured factory, or the default lock if none is set.
+func (r *Resource) createLock() ResourceLock {
+	if r.newLock != nil {
+		return r.newLock()
+	}
+	return NewRWLock()
 }
 
 // Read reads data from the resource within a specified timeout.
 func (r *Resource) Read(ctx context.Context) (string, error) {
+	ctx, cancel := r.withDefaultTimeout(ctx, OpRead)
+	defer cancel()
+
+	ev := r.startEvent(ctx, OpRead)
+	data, err := r.read(ctx, &ev)
+	ev.Value = data
+	r.finishEvent(ev, err)
+	return data, err
+}
+
+// read returns the data under the read lock, recording the lock wait in ev.
+func (r *Resource) read(ctx context.Context, ev *Event) (string, error) {
+	if err := r.checkOpen(); err != nil {
+		return "", err
+	}
 	select {
 	case <-ctx.Done():
 		return "", ctx.Err() // Return error if context is canceled
 	default:
-		r.mu.RLock() // Acquire a read lock
+		wait := r.beginLockWait(OpRead)
+		err := r.mu.RLock(ctx) // Acquire a read lock, giving up if ctx is canceled
+		ev.LockWait = r.endLockWait(wait)
+		if err != nil {
+			return "", wait.fail(err)
+		}
 		defer r.mu.RUnlock()
-		return r.data, nil
+		if ev.sampled {
+			r.metrics.readQueueWait.observe(ev.LockWait)
+		}
+		return r.transformRead(r.data)
 	}
 }
 
+// WithReadLock runs fn on the data while holding the read lock and returns fn's error.
+// This lets callers inspect or hash large values without copying them out. fn must treat
+// the data as read-only and must not call back into the resource's write methods, or it will deadlock.
+func (r *Resource) WithReadLock(ctx context.Context, fn func(data string) error) error {
+	ctx, cancel := r.withDefaultTimeout(ctx, OpRead)
+	defer cancel()
+
+	ev := r.startEvent(ctx, OpRead)
+	err := r.withReadLock(ctx, &ev, fn)
+	r.finishEvent(ev, err)
+	return err
+}
+
+// withReadLock runs fn on the data under the read lock, recording the lock wait in ev.
+func (r *Resource) withReadLock(ctx context.Context, ev *Event, fn func(data string) error) error {
+	if err := r.checkOpen(); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err() // Return error if context is canceled
+	default:
+		wait := r.beginLockWait(OpRead)
+		err := r.mu.RLock(ctx) // Acquire a read lock, giving up if ctx is canceled
+		ev.LockWait = r.endLockWait(wait)
+		if err != nil {
+			return wait.fail(err)
+		}
+		defer r.mu.RUnlock()
+		if ev.sampled {
+			r.metrics.readQueueWait.observe(ev.LockWait)
+		}
+		data, err := r.transformRead(r.data)
+		if err != nil {
+			return err
+		}
+		return callSafely(func() error { return fn(data) })
+	}
+}
+
+// Digest returns the hex-encoded SHA-256 of the data, computed under the read lock.
+func (r *Resource) Digest(ctx context.Context) (string, error) {
+	var digest string
+	err := r.WithReadLock(ctx, func(data string) error {
+		sum := sha256.Sum256([]byte(data))
+		digest = hex.EncodeToString(sum[:])
+		return nil
+	})
+	return digest, err
+}
+
 // Write writes data to the resource within a specified timeout.
 func (r *Resource) Write(ctx context.Context, newData string) error {
+	return r.update(ctx, func(string) (string, error) {
+		return newData, nil
+	})
+}
+
+// AppendLine appends line followed by a newline to the data under the write lock.
+// Concurrent calls never interleave within a line.
+func (r *Resource) AppendLine(ctx context.Context, line string) error {
+	return r.update(ctx, r.visible(func(current string) (string, error) {
+		return current + line + "\n", nil
+	}))
+}
+
+// Swap stores newData and returns the previous data, atomically under the write lock.
+// The previous data is returned as Read would have returned it.
+func (r *Resource) Swap(ctx context.Context, newData string) (string, error) {
+	var old string
+	err := r.update(ctx, r.visible(func(current string) (string, error) {
+		old = current
+		return newData, nil
+	}))
+	if err != nil {
+		return "", err
+	}
+	return old, nil
+}
+
+// errUnchanged is returned by an update function to leave the data untouched.
+var errUnchanged = errors.New("unchanged")
+
+// Patch runs fn on the current data, as Read would return it, under the write lock. fn returns
+// the new data and whether a change is needed; Patch stores it only in that case and reports whether it wrote.
+// A no-op patch does not bump the version or notify subscribers, and is counted as a read.
+func (r *Resource) Patch(ctx context.Context, fn func(current string) (string, bool)) (bool, error) {
+	var changed bool
+	err := r.update(ctx, r.visible(func(current string) (string, error) {
+		next, ok := fn(current)
+		if !ok {
+			return "", errUnchanged
+		}
+		changed = true
+		return next, nil
+	}))
+	if err != nil {
+		return false, err
+	}
+	return changed, nil
+}
+
+// ErrNotNumeric is returned by Increment when the current value is not an integer.
+var ErrNotNumeric = errors.New("resource value is not an integer")
+
+// Increment parses the data as an integer, adds delta, and stores the result under the write lock.
+// It returns the new value, or ErrNotNumeric if the current data is not an integer.
+func (r *Resource) Increment(ctx context.Context, delta int64) (int64, error) {
+	var n int64
+	err := r.update(ctx, r.visible(func(current string) (string, error) {
+		v, err := strconv.ParseInt(current, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%w: %q", ErrNotNumeric, current)
+		}
+		n = v + delta
+		return strconv.FormatInt(n, 10), nil
+	}))
+	return n, err
+}
+
+// EnsureInitialized returns the data if it is non-empty. Otherwise it stores the default computed
+// by compute under the write lock and returns it, so that under concurrent calls compute runs once
+// and every caller sees the same value. If compute fails, nothing is stored and its error is returned.
+func (r *Resource) EnsureInitialized(ctx context.Context, compute func() (string, error)) (string, error) {
+	if data, err := r.Read(ctx); err != nil || data != "" {
+		return data, err // Already initialized, so skip the write lock
+	}
+	var value string
+	err := r.update(ctx, func(current string) (string, error) {
+		if current != "" {
+			var err error
+			value, err = r.transformRead(current) // Initialized while waiting for the lock
+			if err != nil {
+				return "", err
+			}
+			return "", errUnchanged
+		}
+		var err error
+		value, err = compute()
+		return value, err
+	})
+	if err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// visible adapts fn to receive the current data as Read would return it, through the OnRead hooks,
+// so that read-modify-write operations never expose the stored form.
+func (r *Resource) visible(fn func(current string) (string, error)) func(current string) (string, error) {
+	return func(current string) (string, error) {
+		data, err := r.transformRead(current)
+		if err != nil {
+			return "", err
+		}
+		return fn(data)
+	}
+}
+
+// update replaces the data with the value computed by fn from the current data under the write lock.
+// All writes go through update so they share the same checks and bookkeeping.
+// If fn returns errUnchanged, nothing is stored and the operation is recorded as a read.
+func (r *Resource) update(ctx context.Context, fn func(current string) (string, error)) error {
+	return r.updateThen(ctx, fn, nil)
+}
+
+// updateThen is update with a commit hook passed on to applyThen.
+func (r *Resource) updateThen(ctx context.Context, fn func(current string) (string, error), commit func(newData string) error) (err error) {
+	ctx, cancel := r.withDefaultTimeout(ctx, OpWrite)
+	defer cancel()
+
+	ev := r.startEvent(ctx, OpWrite)
+	defer func() { r.finishEvent(ev, err) }()
+
+	if err := r.lockForWrite(ctx, &ev); err != nil {
+		return err
+	}
+	defer r.mu.Unlock()
+	key, dedupe := r.idempotencyKeyFrom(ctx)
+	if dedupe {
+		if prior, ok := r.idempotency.lookup(key); ok {
+			// Already applied: rerun fn on the data it saw the first time so the caller gets the
+			// same result, but store nothing
+			if err := callSafely(func() error { _, err := fn(prior.before); return err }); err != nil && !errors.Is(err, errUnchanged) {
+				return err
+			}
+			ev.Value, err = r.transformRead(r.data)
+			return err
+		}
+	}
+	before := r.data
+	ev.Value, err = r.applyThen(ctx, fn, commit)
+	if errors.Is(err, errUnchanged) {
+		// Nothing was stored, so the operation only observed the data and is recorded as a read
+		ev.Op = OpRead
+		ev.Value, err = r.transformRead(r.data)
+	} else if dedupe && err == nil {
+		r.idempotency.remember(key, before)
+	}
+	return err
+}
+
+// WriteAndDowngrade writes newData and then runs fn on it, as Read would return it, while still holding the lock.
+// The sequence is: acquire the write lock, store newData, atomically downgrade to a read lock,
+// run fn, and release the read lock. Other readers may proceed while fn runs, but no writer
+// can change the value until fn returns, so fn always observes the data it just wrote.
+// fn must not call back into the resource's write methods, or it will deadlock.
+func (r *Resource) WriteAndDowngrade(ctx context.Context, newData string, fn func(data string) error) error {
+	ctx, cancel := r.withDefaultTimeout(ctx, OpWrite)
+	defer cancel()
+
+	ev := r.startEvent(ctx, OpWrite)
+	if err := r.lockForWrite(ctx, &ev); err != nil {
+		r.finishEvent(ev, err)
+		return err
+	}
+	var err error
+	ev.Value, err = r.apply(ctx, func(string) (string, error) {
+		return newData, nil
+	})
+	r.finishEvent(ev, err)
+	if err != nil {
+		r.mu.Unlock()
+		return err
+	}
+
+	d, ok := r.mu.(lockDowngrader)
+	if !ok {
+		defer r.mu.Unlock() // Without downgrades, keep excluding readers too
+	} else {
+		d.Downgrade() // Let other readers in while still excluding writers
+		defer r.mu.RUnlock()
+	}
+	data, err := r.transformRead(r.data)
+	if err != nil {
+		return err
+	}
+	return callSafely(func() error { return fn(data) })
+}
+
+// withDefaultTimeout bounds ctx by the default timeout for op if one is configured and ctx has no deadline.
+func (r *Resource) withDefaultTimeout(ctx context.Context, op string) (context.Context, context.CancelFunc) {
+	timeout := r.defaultTimeout
+	if op == OpRead && r.readTimeout > 0 {
+		timeout = r.readTimeout
+	} else if op == OpWrite && r.writeTimeout > 0 {
+		timeout = r.writeTimeout
+	}
+	if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// lockForWrite acquires the write lock, giving up if ctx is canceled, and reports starvation.
+// The lock wait is recorded in ev. In read-only mode it fails with ErrReadOnly without touching the lock.
+func (r *Resource) lockForWrite(ctx context.Context, ev *Event) error {
+	if err := r.checkOpen(); err != nil {
+		return err
+	}
+	if r.ReadOnly() {
+		return ErrReadOnly
+	}
 	select {
 	case <-ctx.Done():
 		return ctx.Err() // Return error if context is canceled
 	default:
-		r.mu.Lock() // Acquire a write lock
-		defer r.mu.Unlock()
-		r.data = newData
+		wait := r.beginLockWait(OpWrite)
+		err := r.mu.Lock(ctx) // Acquire a write lock
+		ev.LockWait = r.endLockWait(wait)
+		if err != nil {
+			return wait.fail(err)
+		}
+		r.checkStarvation(ev.LockWait)
 		return nil
 	}
 }
 
+// apply stores the value computed by fn from the current data and returns it. The write lock must be held.
+func (r *Resource) apply(ctx context.Context, fn func(current string) (string, error)) (string, error) {
+	return r.applyThen(ctx, fn, nil)
+}
+
+// applyThen is apply with an optional commit hook, run once the new data has passed every check
+// but before it is stored. If the hook fails, the write is aborted and the data left unchanged.
+// If fn returns errUnchanged, nothing is stored and errUnchanged is returned.
+func (r *Resource) applyThen(ctx context.Context, fn func(current string) (string, error), commit func(newData string) error) (string, error) {
+	if r.ReadOnly() {
+		return "", ErrReadOnly // Mode was enabled while waiting for the lock
+	}
+	if r.leased() {
+		return "", ErrLeased
+	}
+	var newData string
+	err := callSafely(func() error {
+		var err error
+		newData, err = fn(r.data)
+		return err
+	})
+	if errors.Is(err, errUnchanged) {
+		return "", errUnchanged // Nothing to store, so skip the version bump and notifications
+	}
+	if err == nil {
+		newData, err = r.transformWrite(newData)
+	}
+	if err == nil {
+		err = r.validateChange(newData)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := r.checkMemory(newData); err != nil {
+		return "", err
+	}
+	if commit != nil {
+		if err := commit(newData); err != nil {
+			return "", err
+		}
+	}
+	r.data = newData
+	atomic.StoreInt64(&r.valueBytes, int64(len(newData)))
+	r.lastWriter, _ = IdentityFromContext(ctx)
+	r.notifyWrite()
+	r.publish(newData)
+	return newData, nil
+}
+
+// LastWriter returns the identity of the client behind the latest write.
+// It is the zero identity if the resource was never written or the writer was anonymous.
+func (r *Resource) LastWriter() WorkerIdentity {
+	_ = r.mu.RLock(context.Background()) // Cannot fail without a deadline
+	defer r.mu.RUnlock()
+	return r.lastWriter
+}
+
+// checkStarvation warns when a write waited longer than the starvation threshold for the lock.
+func (r *Resource) checkStarvation(wait time.Duration) {
+	if r.starvationThreshold <= 0 || wait <= r.starvationThreshold {
+		return
+	}
+	atomic.AddUint64(&r.starvedWrites, 1)
+	fmt.Printf("Warning: write waited %v for the lock (threshold %v)\n", wait, r.starvationThreshold)
+}
+
+// StarvedWrites returns the number of writes that waited longer than the starvation threshold.
+func (r *Resource) StarvedWrites() uint64 {
+	return atomic.LoadUint64(&r.starvedWrites)
+}
+
+// Flush blocks until all writes in flight when it was called have completed, including writes
+// still queued for the lock, so subsequent reads observe the latest value. Writes issued after
+// Flush was called are not waited for.
+func (r *Resource) Flush(ctx context.Context) error {
+	if err := r.checkOpen(); err != nil {
+		return err
+	}
+	r.flightMu.Lock()
+	pending := make(map[*inFlightOp]struct{})
+	for f := range r.inFlight {
+		if f.op == OpWrite {
+			pending[f] = struct{}{}
+		}
+	}
+	for {
+		for f := range pending {
+			if _, ok := r.inFlight[f]; !ok {
+				delete(pending, f)
+			}
+		}
+		if len(pending) == 0 {
+			r.flightMu.Unlock()
+			return nil
+		}
+		if r.landed == nil {
+			r.landed = make(chan struct{})
+		}
+		landed := r.landed
+		r.flightMu.Unlock()
+
+		select {
+		case <-landed:
+		case <-ctx.Done():
+			return ctx.Err() // Return error if context is canceled
+		}
+		r.flightMu.Lock()
+	}
+}
+
+// IsWriteLocked reports whether a writer currently holds the lock, without blocking.
+// The result is advisory only: the lock state may change before the caller acts on it.
+// A writer waiting for the lock also blocks new readers and is reported as holding it.
+func (r *Resource) IsWriteLocked() bool {
+	if r.mu.TryRLock() {
+		r.mu.RUnlock() // Release the probe immediately
+		return false
+	}
+	return true
+}
+
+// WaitQueueDepth returns how many operations are currently waiting to acquire the lock.
+// The value is advisory: it may change as soon as it is read.
+func (r *Resource) WaitQueueDepth() int {
+	if q, ok := r.mu.(lockQueue); ok {
+		return q.Queued()
+	}
+	return 0
+}
+
 // Worker represents a worker that performs read or write operations on the resource.
 type Worker struct {
-	ID       int
+	Identity WorkerIdentity
 	Resource *Resource
+	Timeout  *AdaptiveTimeout // Optional per-operation timeout controller
+	Breaker  *CircuitBreaker  // Optional breaker that stops operations after repeated failures
+	Role     Role             // Operations the worker may issue; the zero value allows all
+
+	// MinRemaining is the least time an operation's context must have left for the worker to start it.
+	// Operations closer to their deadline fail with ErrInsufficientTime instead of doing doomed work.
+	MinRemaining time.Duration
 }
 
 // NewWorker creates a new instance of Worker.
-func NewWorker(id int, resource *Resource) *Worker {
-	return &Worker{ID: id, Resource: resource}
+func NewWorker(identity WorkerIdentity, resource *Resource) *Worker {
+	return &Worker{Identity: identity, Resource: resource}
 }
 
 // ReadFromResource reads data from the resource and prints it.
-func (w *Worker) ReadFromResource(ctx context.Context) {
-	data, err := w.Resource.Read(ctx)
+func (w *Worker) ReadFromResource(ctx context.Context) error {
+	ctx, cancel := w.operationContext(ctx)
+	defer cancel()
+
+	if err := w.preflight(ctx, OpRead); err != nil {
+		fmt.Printf("%s: Read operation failed: %v\n", w.Identity, err)
+		return err
+	}
+	start := time.Now()
+	var data string
+	err := w.guard(func() error {
+		var err error
+		data, err = w.Resource.Read(ctx)
+		return err
+	})
+	w.observe(start, err)
 	if err != nil {
-		fmt.Printf("Worker %d: Read operation failed: %v\n", w.ID, err)
-		return
+		fmt.Printf("%s: Read operation failed: %v\n", w.Identity, err)
+		return err
 	}
-	fmt.Printf("Worker %d reading from resource: %s\n", w.ID, data)
+	fmt.Printf("%s reading from resource: %s\n", w.Identity, data)
+	return nil
 }
 
 // WriteToResource writes data to the resource.
-func (w *Worker) WriteToResource(ctx context.Context, newData string) {
-	err := w.Resource.Write(ctx, newData)
+func (w *Worker) WriteToResource(ctx context.Context, newData string) error {
+	ctx, cancel := w.operationContext(ctx)
+	defer cancel()
+
+	if err := w.preflight(ctx, OpWrite); err != nil {
+		fmt.Printf("%s: Write operation failed: %v\n", w.Identity, err)
+		return err
+	}
+	start := time.Now()
+	err := w.guard(func() error {
+		return w.Resource.Write(ctx, newData)
+	})
+	w.observe(start, err)
 	if err != nil {
-		fmt.Printf("Worker %d: Write operation failed: %v\n", w.ID, err)
-		return
+		fmt.Printf("%s: Write operation failed: %v\n", w.Identity, err)
+		return err
+	}
+	fmt.Printf("%s writing to resource: %s\n", w.Identity, newData)
+	return nil
+}
+
+// operationContext derives the context for a single operation.
+// It attaches the worker's identity and applies the adaptive timeout if configured.
+func (w *Worker) operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = WithIdentity(ctx, w.Identity)
+	if w.Timeout == nil {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, w.Timeout.Timeout())
+}
+
+// ErrInsufficientTime is returned when an operation's context has less time left than the worker's MinRemaining.
+var ErrInsufficientTime = errors.New("not enough time left before the deadline")
+
+// preflight checks that the worker may start op: its role must allow it and ctx must have
+// at least MinRemaining before its deadline.
+func (w *Worker) preflight(ctx context.Context, op string) error {
+	if err := w.Role.authorize(op); err != nil {
+		return err
+	}
+	if w.MinRemaining <= 0 {
+		return nil
 	}
-	fmt.Printf("Worker %d writing to resource: %s\n", w.ID, newData)
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining < w.MinRemaining {
+			return fmt.Errorf("%w: %v left, need %v", ErrInsufficientTime, remaining, w.MinRemaining)
+		}
+	}
+	return nil
+}
+
+// guard runs op through the worker's circuit breaker, if configured.
+func (w *Worker) guard(op func() error) error {
+	if w.Breaker == nil {
+		return op()
+	}
+	return w.Breaker.Do(op)
 }
 
+// observe feeds the latency and outcome of an operation back into the adaptive timeout.
+// Operations short-circuited by the breaker never reached the resource and are ignored.
+func (w *Worker) observe(start time.Time, err error) {
+	if w.Timeout != nil && !errors.Is(err, ErrCircuitOpen) {
+		w.Timeout.Observe(time.Since(start), err)
+	}
+}
+
+// initialData is the value every simulated resource starts with.
+const initialData = "initial data"
+
 // RunSimulation runs the authentication server simulation with the given number of workers and timeout duration.
-func RunSimulation(numWorkers int, timeout time.Duration) {
-	// Create a shared resource
-	resource := NewResource("initial data")
+// The timeout bounds the read and write of each worker cycle.
+// If a maximum duration is configured and exceeded, it returns the partial result with ErrSimulationDeadline.
+// Otherwise it returns the first operation error in fail-fast mode, or all operation errors joined together.
+func RunSimulation(numWorkers int, timeout time.Duration, opts ...SimulationOption) (SimulationResult, error) {
+	cfg := newSimulationConfig(opts)
 
-	// Create a pool of workers
+	// Create the shared resources
+	resources := make([]*Resource, cfg.numResources)
+	for i := range resources {
+		resources[i] = cfg.newResource(initialData)
+	}
+
+	// Create a pool of workers, assigned to the resources round-robin
 	workers := make([]*Worker, numWorkers)
 	for i := 0; i < numWorkers; i++ {
-		workers[i] = NewWorker(i+1, resource)
+		workers[i] = NewWorker(WorkerIdentity{ID: i + 1}, resources[i%len(resources)])
+		workers[i].Role = cfg.role(i)
+		workers[i].MinRemaining = cfg.minRemaining
 	}
 
-	// Set timeout for read and write operations
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+	// Bound the whole run by the wall-clock cap, if any
+	simCtx, stop := context.WithCancel(context.Background())
+	if cfg.maxDuration > 0 {
+		simCtx, stop = context.WithTimeout(context.Background(), cfg.maxDuration)
+	}
+	defer stop()
+
+	// Allow fail-fast mode to cancel every worker at once
+	runCtx, abort := context.WithCancel(simCtx)
+	defer abort()
 
 	// Simulate concurrent read and write operations with timeout
-	var wg sync.WaitGroup
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex // Guards result and errs while workers are running
+		result = newSimulationResult(len(resources))
+		errs   []error
+		limit  = newSemaphore(cfg.maxConcurrency) // Server capacity shared by all workers
+		retry  = retryPolicy{
+			budget:   newRetryBudget(cfg.retryBudget), // Retry allowance shared by all workers
+			attempts: cfg.maxAttempts,
+			deadline: cfg.attemptDeadline,
+		}
+	)
+	fail := func(err error) {
+		if err == nil {
+			return
+		}
+		errs = append(errs, err)
+		if cfg.failFast && len(errs) == 1 {
+			abort() // Cancel the remaining workers on the first error
+		}
+	}
+	runStart := time.Now()
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
-		go func(worker *Worker) {
+		go func(worker *Worker, shard int, delay time.Duration) {
 			defer wg.Done()
 
-			// Perform read operation
-			worker.ReadFromResource(ctx)
+			// Wait for this worker's arrival time, if staggered
+			if delay > 0 {
+				select {
+				case <-time.After(delay):
+				case <-runCtx.Done():
+					return
+				}
+			}
+
+			for {
+				// Set timeout for this cycle's read and write operations, so later cycles get the full timeout too
+				ctx, cancel := context.WithTimeout(runCtx, timeout)
+
+				// Perform read operation
+				readID := NewOperationID()
+				measured := cfg.measured(time.Since(runStart))
+				err := retry.run(ctx, func(ctx context.Context) error {
+					return limit.run(ctx, func() error {
+						return cfg.faults.run(ctx, func() error {
+							return worker.ReadFromResource(WithOperationID(ctx, readID))
+						})
+					})
+				})
+				if measured {
+					mu.Lock()
+					result.recordRead(shard, err)
+					fail(err)
+					mu.Unlock()
+				}
+
+				// Introduce some delay to simulate real-world scenarios
+				select {
+				case <-time.After(time.Second):
+				case <-runCtx.Done():
+				}
 
-			// Introduce some delay to simulate real-world scenarios
-			time.Sleep(time.Second)
+				// Perform write operation
+				newData := fmt.Sprintf("new data written by %s", worker.Identity)
+				measured = cfg.measured(time.Since(runStart))
+				err = retry.run(ctx, func(ctx context.Context) error {
+					return limit.run(ctx, func() error {
+						return cfg.faults.run(ctx, func() error {
+							return worker.WriteToResource(WithParentOperation(ctx, readID), newData) // The write is informed by the read
+						})
+					})
+				})
+				if measured {
+					mu.Lock()
+					result.recordWrite(shard, worker.Identity.ID, err)
+					fail(err)
+					mu.Unlock()
+				}
+				cancel()
 
-			// Perform write operation
-			newData := fmt.Sprintf("new data written by Worker %d", worker.ID)
-			worker.WriteToResource(ctx, newData)
-		}(workers[i])
+				// Keep cycling until the measurement phase is over, if one is configured
+				if !cfg.repeat(time.Since(runStart)) || runCtx.Err() != nil {
+					return
+				}
+			}
+		}(workers[i], i%len(resources), cfg.arrivalDelay(i, numWorkers))
 	}
 
-	// Wait for all workers to finish
-	wg.Wait()
+	// Wait for all workers to finish or for the wall-clock cap to expire
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-simCtx.Done():
+		mu.Lock()
+		partial := result.clone() // Workers may still be recording
+		mu.Unlock()
+		partial.Retries = retry.budget.spent()
+		partial.setDuration(cfg.countedDuration(time.Since(runStart)))
+		partial.Events = collectEvents(resources)
+		partial.ReadLatency = readLatency(resources)
+		return partial, ErrSimulationDeadline
+	}
 
-	// Final state of the resource
-	data, err := resource.Read(context.Background())
-	if err != nil {
-		fmt.Printf("Error reading final state of the resource: %v\n", err)
-		return
+	// Final state of the resources
+	result.Retries = retry.budget.spent()
+	result.setDuration(cfg.countedDuration(time.Since(runStart)))
+	result.Events = collectEvents(resources)
+	result.ReadLatency = readLatency(resources)
+	for i, resource := range resources {
+		data, err := resource.Read(context.Background())
+		if err != nil {
+			fmt.Printf("Error reading final state of the resource: %v\n", err)
+			return result, err
+		}
+		if len(resources) == 1 {
+			fmt.Println("Final state of the resource:", data)
+		} else {
+			fmt.Printf("Final state of resource %d: %s\n", i, data)
+		}
+		result.Resources[i].FinalValue = data
+	}
+	result.FinalValue = result.Resources[0].FinalValue
+	result.Finished = time.Now()
+	if cfg.failFast && len(errs) > 0 {
+		return result, errs[0]
 	}
-	fmt.Println("Final state of the resource:", data)
+	return result, errors.Join(errs...)
 }
 
-func main(){
+func main() {
 	RunSimulation(3, time.Duration(100))
 }
@@ -0,0 +1,615 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPatchNoChange(t *testing.T) {
+	r := NewResource("initial", WithOperationLog(8))
+	sub := r.Subscribe(SubscribeOptions{})
+	defer sub.Unsubscribe()
+	ctx := context.Background()
+
+	changed, err := r.Patch(ctx, func(current string) (string, bool) { return current, false })
+	if err != nil || changed {
+		t.Fatalf("no-op patch: got changed %v, err %v", changed, err)
+	}
+	swapped, err := r.CompareAndSwap(ctx, "stale", "next")
+	if err != nil || swapped {
+		t.Fatalf("CAS with a stale value: got swapped %v, err %v", swapped, err)
+	}
+
+	if v := r.Version(); v != 0 {
+		t.Fatalf("version %d after only unchanged updates, want 0", v)
+	}
+	if updates := sub.DrainNotifications(DrainAll); len(updates) != 0 {
+		t.Fatalf("subscriber notified of %v", updates)
+	}
+	if m := r.Metrics(); m.Writes != 0 || m.Reads != 2 {
+		t.Fatalf("got %d writes and %d reads, want the unchanged updates counted as 2 reads", m.Writes, m.Reads)
+	}
+	for _, rec := range r.RecentOperations() {
+		if rec.Op != OpRead || rec.Value != "initial" {
+			t.Errorf("recorded %s %q, want a read of the unchanged value", rec.Op, rec.Value)
+		}
+	}
+
+	replayed, err := ReplayLog(ctx, r.RecentOperations())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Writes != 0 {
+		t.Fatalf("replay performed %d writes, want none", replayed.Writes)
+	}
+}
+
+func TestPatchChange(t *testing.T) {
+	r := NewResource("a")
+	changed, err := r.Patch(context.Background(), func(current string) (string, bool) { return current + "b", true })
+	if err != nil || !changed {
+		t.Fatalf("got changed %v, err %v", changed, err)
+	}
+	if data, _ := r.Read(context.Background()); data != "ab" || r.Version() != 1 {
+		t.Fatalf("got %q at version %d, want \"ab\" at version 1", data, r.Version())
+	}
+	if m := r.Metrics(); m.Writes != 1 {
+		t.Fatalf("got %d writes, want 1", m.Writes)
+	}
+}
+
+func TestFlushWaitsForQueuedWrites(t *testing.T) {
+	r := NewResource("initial")
+	ctx := context.Background()
+
+	_, release, err := r.BeginSnapshot(ctx) // Block writers so they queue
+	if err != nil {
+		t.Fatal(err)
+	}
+	const writes = 3
+	for i := 0; i < writes; i++ {
+		go r.Write(ctx, fmt.Sprint(i))
+	}
+	for r.WaitQueueDepth() < writes {
+		time.Sleep(time.Millisecond)
+	}
+
+	flushed := make(chan uint64, 1)
+	go func() {
+		if err := r.Flush(ctx); err != nil {
+			t.Error(err)
+		}
+		flushed <- r.Version()
+	}()
+	select {
+	case <-flushed:
+		t.Fatal("Flush returned while writes were still queued")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case v := <-flushed:
+		if v != writes {
+			t.Fatalf("Flush returned at version %d, want all %d writes applied", v, writes)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Flush did not return after the writes were applied")
+	}
+}
+
+func TestFlushWithoutWrites(t *testing.T) {
+	r := NewResource("initial")
+	if err := r.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// captureOutput runs fn and returns what it printed to standard output.
+func captureOutput(t *testing.T, fn func()) string {
+	t.Helper()
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = pw
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(pr)
+		out <- string(b)
+	}()
+	defer func() { os.Stdout = stdout }()
+
+	fn()
+	pw.Close()
+	return <-out
+}
+
+func TestStarvationWarning(t *testing.T) {
+	const threshold = 5 * time.Millisecond
+	r := NewResource("initial", WithStarvationThreshold(threshold))
+	ctx := context.Background()
+
+	output := captureOutput(t, func() {
+		// Flood the resource with slow readers, then write behind them
+		var wg sync.WaitGroup
+		held := make(chan struct{}, 8)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				r.WithReadLock(ctx, func(string) error {
+					held <- struct{}{}
+					time.Sleep(4 * threshold)
+					return nil
+				})
+			}()
+		}
+		<-held
+		if err := r.Write(ctx, "starved"); err != nil {
+			t.Error(err)
+		}
+		wg.Wait()
+	})
+
+	if n := r.StarvedWrites(); n != 1 {
+		t.Fatalf("got %d starved writes, want 1", n)
+	}
+	if !strings.Contains(output, "Warning: write waited") {
+		t.Fatalf("no starvation warning in output %q", output)
+	}
+}
+
+func TestNoStarvationWarningForQuickWrites(t *testing.T) {
+	r := NewResource("initial", WithStarvationThreshold(time.Second))
+	output := captureOutput(t, func() {
+		r.Write(context.Background(), "quick")
+	})
+	if r.StarvedWrites() != 0 || output != "" {
+		t.Fatalf("uncontended write reported as starved: %q", output)
+	}
+}
+
+func TestIsWriteLocked(t *testing.T) {
+	r := NewResource("initial")
+	if r.IsWriteLocked() {
+		t.Fatal("idle resource reported as write-locked")
+	}
+
+	tx, err := r.Begin(context.Background()) // Holds the write lock until rolled back
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.IsWriteLocked() {
+		t.Fatal("write lock held but not reported")
+	}
+	tx.Rollback()
+	if r.IsWriteLocked() {
+		t.Fatal("still reported as write-locked after release")
+	}
+
+	_, release, err := r.BeginSnapshot(context.Background()) // A read lock is not a write lock
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	if r.IsWriteLocked() {
+		t.Fatal("read lock reported as a write lock")
+	}
+}
+
+func TestAppendLineConcurrent(t *testing.T) {
+	r := NewResource("")
+	ctx := context.Background()
+	const writers, lines = 8, 50
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < lines; i++ {
+				if err := r.AppendLine(ctx, fmt.Sprintf("writer %d line %d", w, i)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	data, _ := r.Read(ctx)
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSuffix(data, "\n"), "\n") {
+		seen[line] = true
+	}
+	if len(seen) != writers*lines {
+		t.Fatalf("got %d distinct lines, want %d", len(seen), writers*lines)
+	}
+	for w := 0; w < writers; w++ {
+		for i := 0; i < lines; i++ {
+			if line := fmt.Sprintf("writer %d line %d", w, i); !seen[line] {
+				t.Fatalf("line %q missing or torn", line)
+			}
+		}
+	}
+}
+
+func TestWriteAndDowngradeLetsReadersIn(t *testing.T) {
+	r := NewResource("initial")
+	ctx := context.Background()
+
+	writeDone := make(chan error, 1)
+	err := r.WriteAndDowngrade(ctx, "written", func(data string) error {
+		if data != "written" {
+			t.Errorf("holder saw %q", data)
+		}
+		readCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		if got, err := r.Read(readCtx); err != nil || got != "written" {
+			t.Errorf("concurrent read while downgraded: got %q (%v)", got, err)
+		}
+
+		go func() { writeDone <- r.Write(ctx, "later") }()
+		select {
+		case <-writeDone:
+			t.Error("writer got in while the downgraded lock was held")
+		case <-time.After(20 * time.Millisecond):
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-writeDone; err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := r.Read(ctx); data != "later" {
+		t.Fatalf("got %q after the downgraded lock was released", data)
+	}
+}
+
+// blockedFor runs op against a resource whose write lock is held and returns its duration and error.
+func blockedFor(t *testing.T, r *Resource, op func() error) (time.Duration, error) {
+	t.Helper()
+	// An explicit deadline keeps the resource's own timeouts from aborting the transaction early
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	tx, err := r.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	start := time.Now()
+	err = op()
+	return time.Since(start), err
+}
+
+func TestDefaultTimeoutAppliesWithoutDeadline(t *testing.T) {
+	r := NewResource("initial", WithDefaultTimeout(20*time.Millisecond))
+
+	elapsed, err := blockedFor(t, r, func() error {
+		_, err := r.Read(context.Background())
+		return err
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want DeadlineExceeded from the default timeout", err)
+	}
+	if elapsed > time.Second {
+		t.Fatalf("read gave up after %v", elapsed)
+	}
+}
+
+func TestDefaultTimeoutKeepsCallerDeadline(t *testing.T) {
+	r := NewResource("initial", WithDefaultTimeout(time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := blockedFor(t, r, func() error { return r.Write(ctx, "x") })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the caller's deadline to apply", err)
+	}
+}
+
+func TestWithReadLockChecksumBlocksWrites(t *testing.T) {
+	r := NewResource("initial")
+	ctx := context.Background()
+
+	writeDone := make(chan error, 1)
+	var sum uint32
+	err := r.WithReadLock(ctx, func(data string) error {
+		go func() { writeDone <- r.Write(ctx, "changed") }()
+		for i := 0; i < len(data); i++ {
+			sum = sum*31 + uint32(data[i])
+			time.Sleep(2 * time.Millisecond) // Give the writer time to slip in if it could
+		}
+		select {
+		case <-writeDone:
+			t.Error("write landed while the read lock was held")
+		default:
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want uint32
+	for _, c := range []byte("initial") {
+		want = want*31 + uint32(c)
+	}
+	if sum != want {
+		t.Fatalf("checksum %d, want %d for the unchanged value", sum, want)
+	}
+	if err := <-writeDone; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWithReadLockReturnsCallbackError(t *testing.T) {
+	r := NewResource("initial")
+	errBad := errors.New("bad value")
+	if err := r.WithReadLock(context.Background(), func(string) error { return errBad }); err != errBad {
+		t.Fatalf("got %v, want the callback's error", err)
+	}
+}
+
+func TestIncrementConcurrent(t *testing.T) {
+	r := NewResource("0")
+	ctx := context.Background()
+	const workers, perWorker = 8, 100
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(delta int64) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				if _, err := r.Increment(ctx, delta); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(int64(w + 1))
+	}
+	wg.Wait()
+
+	// Worker w adds w+1 each time, so the total is perWorker * (1 + 2 + ... + workers)
+	want := fmt.Sprint(perWorker * workers * (workers + 1) / 2)
+	if data, _ := r.Read(ctx); data != want {
+		t.Fatalf("got %s, want %s", data, want)
+	}
+}
+
+func TestIncrementNotNumeric(t *testing.T) {
+	r := NewResource("initial")
+	if _, err := r.Increment(context.Background(), 1); !errors.Is(err, ErrNotNumeric) {
+		t.Fatalf("got %v, want ErrNotNumeric", err)
+	}
+	if data, _ := r.Read(context.Background()); data != "initial" {
+		t.Fatalf("failed increment changed the data to %q", data)
+	}
+}
+
+func TestDigestTracksWrites(t *testing.T) {
+	r := NewResource("initial")
+	ctx := context.Background()
+
+	before, err := r.Digest(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Write(ctx, "changed")
+	after, err := r.Digest(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before == after {
+		t.Fatal("digest unchanged after a write")
+	}
+	sum := sha256.Sum256([]byte("changed"))
+	if want := hex.EncodeToString(sum[:]); after != want {
+		t.Fatalf("got %s, want %s", after, want)
+	}
+}
+
+func TestPerOperationTimeouts(t *testing.T) {
+	const readTimeout, writeTimeout = 20 * time.Millisecond, 200 * time.Millisecond
+	r := NewResource("initial", WithDefaultTimeout(time.Hour), WithReadTimeout(readTimeout), WithWriteTimeout(writeTimeout))
+
+	readTook, err := blockedFor(t, r, func() error {
+		_, err := r.Read(context.Background())
+		return err
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("read: got %v, want DeadlineExceeded", err)
+	}
+	writeTook, err := blockedFor(t, r, func() error { return r.Write(context.Background(), "x") })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("write: got %v, want DeadlineExceeded", err)
+	}
+
+	if readTook >= writeTimeout {
+		t.Errorf("read gave up after %v, want the %v read timeout", readTook, readTimeout)
+	}
+	if writeTook < writeTimeout {
+		t.Errorf("write gave up after %v, want the %v write timeout", writeTook, writeTimeout)
+	}
+}
+
+func TestSwapConcurrent(t *testing.T) {
+	r := NewResource("initial")
+	ctx := context.Background()
+	const swappers = 32
+
+	olds := make(chan string, swappers)
+	var wg sync.WaitGroup
+	for i := 0; i < swappers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			old, err := r.Swap(ctx, fmt.Sprint("value ", i))
+			if err != nil {
+				t.Error(err)
+			}
+			olds <- old
+		}(i)
+	}
+	wg.Wait()
+	close(olds)
+
+	// The swaps form a chain: every value but the last is returned exactly once as a previous value
+	seen := make(map[string]int)
+	for old := range olds {
+		seen[old]++
+	}
+	final, _ := r.Read(ctx)
+	seen[final]++
+	if len(seen) != swappers+1 {
+		t.Fatalf("got %d distinct values, want %d", len(seen), swappers+1)
+	}
+	for value, n := range seen {
+		if n != 1 {
+			t.Errorf("value %q seen %d times", value, n)
+		}
+	}
+	if seen["initial"] != 1 {
+		t.Error("initial value was lost")
+	}
+}
+
+func TestWaitQueueDepth(t *testing.T) {
+	r := NewResource("initial")
+	tx, err := r.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := r.WaitQueueDepth(); d != 0 {
+		t.Fatalf("depth %d with only the holder, want 0", d)
+	}
+
+	// Queue readers and writers, some of which give up before the lock is released
+	const waiters = 4
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < waiters; i++ {
+		wg.Add(2)
+		go func() { defer wg.Done(); r.Read(context.Background()) }()
+		go func() { defer wg.Done(); r.Write(ctx, "abandoned") }()
+	}
+	deadline := time.Now().Add(time.Second)
+	for r.WaitQueueDepth() != 2*waiters {
+		if time.Now().After(deadline) {
+			t.Fatalf("depth %d, want %d queued operations", r.WaitQueueDepth(), 2*waiters)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	for r.WaitQueueDepth() != waiters {
+		if time.Now().After(deadline) {
+			t.Fatalf("depth %d after the writers gave up, want %d", r.WaitQueueDepth(), waiters)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	tx.Rollback()
+	wg.Wait()
+	if d := r.WaitQueueDepth(); d != 0 {
+		t.Fatalf("depth %d after every waiter finished, want 0", d)
+	}
+}
+
+func TestEnsureInitializedComputesOnce(t *testing.T) {
+	r := NewResource("")
+	ctx := context.Background()
+	const callers = 16
+
+	var (
+		computed int32
+		wg       sync.WaitGroup
+		values   = make(chan string, callers)
+	)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := r.EnsureInitialized(ctx, func() (string, error) {
+				n := atomic.AddInt32(&computed, 1)
+				time.Sleep(time.Millisecond) // Widen the window for a second computation
+				return fmt.Sprint("default ", n), nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+			values <- v
+		}()
+	}
+	wg.Wait()
+	close(values)
+
+	if computed != 1 {
+		t.Fatalf("compute ran %d times, want once", computed)
+	}
+	for v := range values {
+		if v != "default 1" {
+			t.Fatalf("a caller saw %q, want the single computed default", v)
+		}
+	}
+	if data, _ := r.Read(ctx); data != "default 1" {
+		t.Fatalf("stored %q", data)
+	}
+}
+
+func TestEnsureInitializedKeepsExistingAndFailures(t *testing.T) {
+	ctx := context.Background()
+	r := NewResource("present")
+	v, err := r.EnsureInitialized(ctx, func() (string, error) {
+		t.Error("compute ran for an initialized resource")
+		return "", nil
+	})
+	if err != nil || v != "present" {
+		t.Fatalf("got %q (%v)", v, err)
+	}
+
+	r = NewResource("")
+	errCompute := errors.New("no default")
+	if _, err := r.EnsureInitialized(ctx, func() (string, error) { return "", errCompute }); err != errCompute {
+		t.Fatalf("got %v, want the compute error", err)
+	}
+	if r.Version() != 0 {
+		t.Fatal("a failed computation was stored")
+	}
+}
+
+func TestWorkerSkipsOperationsNearDeadline(t *testing.T) {
+	r := NewResource("initial")
+	w := NewWorker(WorkerIdentity{ID: 1}, r)
+	w.MinRemaining = time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var err error
+	captureOutput(t, func() { err = w.WriteToResource(ctx, "doomed") })
+	if !errors.Is(err, ErrInsufficientTime) {
+		t.Fatalf("got %v, want ErrInsufficientTime", err)
+	}
+	if data, _ := r.Read(context.Background()); data != "initial" {
+		t.Fatalf("stored %q, want the write skipped", data)
+	}
+
+	// Without a deadline there is nothing to run out of
+	captureOutput(t, func() { err = w.WriteToResource(context.Background(), "fine") })
+	if err != nil {
+		t.Fatal(err)
+	}
+}